@@ -6,19 +6,44 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"runtime/debug"
 	"syscall"
+	"time"
 
+	grpcimpl "github.com/actoris/actoris/services/identity-cloud/internal/grpc"
+	"github.com/actoris/actoris/services/identity-cloud/internal/idempotency"
 	"github.com/actoris/actoris/services/identity-cloud/internal/repository"
+	"github.com/actoris/actoris/services/identity-cloud/internal/resolver"
 	"github.com/actoris/actoris/services/identity-cloud/internal/service"
+	"github.com/actoris/actoris/services/identity-cloud/internal/webhooks"
 	"github.com/actoris/actoris/services/identity-cloud/pkg/config"
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 )
 
+// walletIdempotencyResponses maps each wallet-mutating RPC's full gRPC
+// method name to a constructor for its response type, so the idempotency
+// interceptor can decode a cached response back into the right type on
+// replay. These paths match what protoc-gen-go-grpc would generate from the
+// identitycloud.IdentityService definition once proto codegen is wired up.
+var walletIdempotencyResponses = idempotency.ResponseFactories{
+	"/identitycloud.IdentityService/CreditWallet":  func() any { return &grpcimpl.CreditWalletResponse{} },
+	"/identitycloud.IdentityService/DebitWallet":   func() any { return &grpcimpl.DebitWalletResponse{} },
+	"/identitycloud.IdentityService/LockWallet":    func() any { return &grpcimpl.LockWalletResponse{} },
+	"/identitycloud.IdentityService/ReleaseWallet": func() any { return &grpcimpl.ReleaseWalletResponse{} },
+}
+
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -30,8 +55,17 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// OpenTelemetry tracing. Every gRPC call, and every Neo4j call the
+	// repository makes through withSession (see
+	// internal/repository/tracing.go), gets a span through this provider;
+	// calls that run inside a repository Tx (Begin/Commit) get no span.
+	tracerProvider, err := setupTracing(ctx, cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Failed to set up tracing: %v", err)
+	}
+
 	// Initialize Neo4j repository
-	repo, err := repository.NewNeo4jRepository(ctx, cfg.Neo4j.URI, cfg.Neo4j.Username, cfg.Neo4j.Password)
+	repo, err := repository.NewNeo4jRepository(ctx, cfg.Neo4j.URIs, cfg.Neo4j.Username, cfg.Neo4j.Password)
 	if err != nil {
 		log.Fatalf("Failed to connect to Neo4j: %v", err)
 	}
@@ -44,20 +78,68 @@ func main() {
 		log.Printf("Warning: failed to initialize schema: %v", err)
 	}
 
+	// Webhook dispatcher. It runs until ctx is canceled, delivering events to
+	// every subscription registered in Neo4j.
+	webhookDispatcher := webhooks.NewDispatcher(ctx, repo, nil, cfg.Service.WebhookMaxAttempts, cfg.Service.WebhookBaseBackoff)
+
+	// Resolves did:web identities that were never created through this
+	// service, so signatures from externally-hosted DIDs can still be
+	// verified.
+	didResolver := resolver.New(nil)
+
 	// Initialize service
-	svc := service.NewIdentityService(repo)
+	svc := service.NewIdentityService(repo, cfg.Service.GovernanceDIDs, cfg.Service.MinCohortSize, cfg.Service.MasterSeed, cfg.Service.TrustScoreThresholds, webhookDispatcher, didResolver)
+
+	// Idempotency store for wallet-mutating RPCs. MemoryStore is fine for a
+	// single replica; deployments running several replicas should swap in
+	// idempotency.NewPostgresStore so keys are shared across instances.
+	idempotencyStore := idempotency.NewMemoryStore()
+
+	// recoveryHandler converts a panicking handler into a codes.Internal
+	// error instead of crashing the whole server, logging a stack trace so
+	// the panic is still diagnosable.
+	recoveryHandler := func(ctx context.Context, p any) error {
+		log.Printf("panic recovered in gRPC handler: %v\n%s", p, debug.Stack())
+		return status.Errorf(codes.Internal, "internal error")
+	}
+	recoveryOpts := []recovery.Option{
+		recovery.WithRecoveryHandlerContext(recoveryHandler),
+	}
+
+	// grpc_prometheus' default handling-time histogram buckets are disabled
+	// unless explicitly enabled; per-RPC latency is worth the extra series.
+	grpc_prometheus.EnableHandlingTimeHistogram()
 
-	// Create gRPC server
+	// Create gRPC server. Interceptors run in order: panic recovery first so
+	// nothing downstream of it can crash the process, then metrics and the
+	// existing idempotency check. Tracing is wired as a stats handler rather
+	// than an interceptor, since otelgrpc's interceptor functions were
+	// dropped in favor of stats handlers in the contrib version this uses.
 	grpcServer := grpc.NewServer(
 		grpc.MaxRecvMsgSize(cfg.Server.MaxRecvMsgSize),
 		grpc.MaxSendMsgSize(cfg.Server.MaxSendMsgSize),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(
+			recovery.UnaryServerInterceptor(recoveryOpts...),
+			grpc_prometheus.UnaryServerInterceptor,
+			idempotency.UnaryServerInterceptor(idempotencyStore, cfg.Service.IdempotencyTTL, walletIdempotencyResponses),
+		),
+		grpc.ChainStreamInterceptor(
+			recovery.StreamServerInterceptor(recoveryOpts...),
+			grpc_prometheus.StreamServerInterceptor,
+		),
 	)
 
-	// Register health service
+	// Register health service. Its serving status tracks Neo4j endpoint
+	// health rather than being pinned to SERVING: the service can still
+	// answer as long as at least one configured endpoint is reachable.
 	healthServer := health.NewServer()
 	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
-	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
-	healthServer.SetServingStatus("identity", grpc_health_v1.HealthCheckResponse_SERVING)
+	go watchNeo4jHealth(ctx, repo, healthServer)
+
+	// Periodically sweep expired HCOperation idempotency records so the
+	// append-only dedup ledger doesn't grow unbounded.
+	go watchIdempotencyPurge(ctx, repo, cfg.Service.IdempotencyPurgeInterval, cfg.Service.IdempotencyPurgeMaxAge)
 
 	// Enable reflection for development
 	reflection.Register(grpcServer)
@@ -67,6 +149,23 @@ func main() {
 	// pb.RegisterIdentityServiceServer(grpcServer, identityServer)
 	_ = svc // Use service (will be used when proto registration is added)
 
+	// grpc_prometheus.Register walks the server's registered services to set
+	// up per-method metrics; it must run after every service is registered
+	// but before Serve starts handling requests.
+	grpc_prometheus.Register(grpcServer)
+
+	// Metrics HTTP listener, separate from the gRPC port so scraping never
+	// competes with the gRPC request path.
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", promhttp.Handler())
+	metricsServer := &http.Server{Addr: cfg.Metrics.Address(), Handler: metricsMux}
+	go func() {
+		log.Printf("IdentityCloud metrics server starting on %s", cfg.Metrics.Address())
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("Metrics server error: %v", err)
+		}
+	}()
+
 	// Create listener
 	listener, err := net.Listen("tcp", cfg.Server.Address())
 	if err != nil {
@@ -116,9 +215,72 @@ func main() {
 		grpcServer.Stop()
 	}
 
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Metrics server shutdown error: %v", err)
+	}
+
+	if err := tracerProvider.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Tracer provider shutdown error: %v", err)
+	}
+
 	log.Println("IdentityCloud shutdown complete")
 }
 
+// neo4jHealthCheckInterval is how often watchNeo4jHealth re-probes every
+// configured Neo4j endpoint to refresh the gRPC health service's status.
+const neo4jHealthCheckInterval = 15 * time.Second
+
+// watchNeo4jHealth periodically probes repo's endpoints and reflects the
+// result onto healthServer: SERVING as soon as at least one endpoint
+// responds, NOT_SERVING only once every endpoint is down. It runs until ctx
+// is canceled.
+func watchNeo4jHealth(ctx context.Context, repo *repository.Neo4jRepository, healthServer *health.Server) {
+	ticker := time.NewTicker(neo4jHealthCheckInterval)
+	defer ticker.Stop()
+
+	setStatus := func() {
+		status := grpc_health_v1.HealthCheckResponse_NOT_SERVING
+		for _, ep := range repo.HealthCheck(ctx) {
+			if ep.Healthy {
+				status = grpc_health_v1.HealthCheckResponse_SERVING
+				break
+			}
+		}
+		healthServer.SetServingStatus("", status)
+		healthServer.SetServingStatus("identity", status)
+	}
+
+	setStatus()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			setStatus()
+		}
+	}
+}
+
+// watchIdempotencyPurge periodically deletes HCOperation idempotency records
+// older than maxAge, bounding the graph growth from the append-only dedup
+// ledger. It runs until ctx is canceled; a failed sweep is logged and
+// retried on the next tick rather than stopping the loop.
+func watchIdempotencyPurge(ctx context.Context, repo *repository.Neo4jRepository, interval, maxAge time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := repo.PurgeExpiredOperations(ctx, maxAge); err != nil {
+				log.Printf("Failed to purge expired idempotency operations: %v", err)
+			}
+		}
+	}
+}
+
 // initializeSchema sets up Neo4j constraints and indexes
 func initializeSchema(ctx context.Context, repo *repository.Neo4jRepository) error {
 	// Create constraints for UnifiedID nodes
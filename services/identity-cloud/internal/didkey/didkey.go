@@ -0,0 +1,161 @@
+// Package didkey implements the W3C did:key method: a multicodec-prefixed
+// public key, base58btc-encoded and prefixed with the multibase "z" marker.
+package didkey
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/actoris/actoris/services/identity-cloud/internal/domain"
+)
+
+// base58btcAlphabet is the Bitcoin base58 alphabet did:key's multibase "z"
+// encoding uses.
+const base58btcAlphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// multicodecPrefix gives the multicodec bytes that precede the raw public
+// key for each key type did:key supports.
+var multicodecPrefix = map[domain.KeyType][]byte{
+	domain.KeyTypeEd25519:   {0xed, 0x01},
+	domain.KeyTypeSecp256k1: {0xe7, 0x01},
+	// P-256's multicodec code is 0x1200, whose LEB128 varint encoding is two
+	// bytes with the first byte's continuation bit set: {0x80, 0x24}, not the
+	// raw big-endian bytes of 0x1200.
+	domain.KeyTypeP256: {0x80, 0x24},
+}
+
+// Encode produces a did:key identifier for publicKey: multicodec prefix +
+// raw key bytes, base58btc-encoded and prefixed with "did:key:z".
+func Encode(keyType domain.KeyType, publicKey []byte) (string, error) {
+	prefix, ok := multicodecPrefix[keyType]
+	if !ok {
+		return "", fmt.Errorf("unsupported did:key key type: %s", keyType)
+	}
+
+	encoded := make([]byte, 0, len(prefix)+len(publicKey))
+	encoded = append(encoded, prefix...)
+	encoded = append(encoded, publicKey...)
+
+	return "did:key:z" + base58btcEncode(encoded), nil
+}
+
+// Decode parses a did:key identifier back into its key type and raw public
+// key bytes, since a did:key is self-certifying: the DID string itself is
+// the encoded key.
+func Decode(did string) (domain.KeyType, []byte, error) {
+	const prefix = "did:key:z"
+	if !strings.HasPrefix(did, prefix) {
+		return domain.KeyTypeUnspecified, nil, fmt.Errorf("not a did:key identifier: %s", did)
+	}
+	return decodeMulticodec(strings.TrimPrefix(did, prefix))
+}
+
+// DecodeMultibase parses a standalone multibase value in the same encoding
+// did:key uses (the "z" base58btc marker followed by a multicodec-prefixed
+// key), as found in a DID document's publicKeyMultibase field outside of a
+// did:key identifier.
+func DecodeMultibase(value string) (domain.KeyType, []byte, error) {
+	const prefix = "z"
+	if !strings.HasPrefix(value, prefix) {
+		return domain.KeyTypeUnspecified, nil, fmt.Errorf("not a base58btc multibase value: %s", value)
+	}
+	return decodeMulticodec(strings.TrimPrefix(value, prefix))
+}
+
+// ParseDIDKey decodes an Ed25519 did:key identifier directly to its public
+// key, with no repository lookup required, since a did:key identifier is
+// the encoded key itself.
+func ParseDIDKey(did string) (ed25519.PublicKey, error) {
+	keyType, publicKey, err := Decode(did)
+	if err != nil {
+		return nil, err
+	}
+	if keyType != domain.KeyTypeEd25519 {
+		return nil, fmt.Errorf("did:key %s is not an Ed25519 key", did)
+	}
+	return ed25519.PublicKey(publicKey), nil
+}
+
+func decodeMulticodec(b58 string) (domain.KeyType, []byte, error) {
+	decoded, err := base58btcDecode(b58)
+	if err != nil {
+		return domain.KeyTypeUnspecified, nil, fmt.Errorf("invalid base58btc in did:key: %w", err)
+	}
+
+	for keyType, p := range multicodecPrefix {
+		if len(decoded) > len(p) && byteSlicesEqual(decoded[:len(p)], p) {
+			return keyType, decoded[len(p):], nil
+		}
+	}
+	return domain.KeyTypeUnspecified, nil, fmt.Errorf("unrecognized multicodec prefix in did:key: %s", b58)
+}
+
+func byteSlicesEqual(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// base58btcEncode encodes data with the Bitcoin base58 alphabet, preserving
+// leading zero bytes as leading '1's.
+func base58btcEncode(data []byte) string {
+	leadingZeros := 0
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		leadingZeros++
+	}
+
+	n := new(big.Int).SetBytes(data)
+	base := big.NewInt(58)
+	mod := new(big.Int)
+
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		out = append(out, base58btcAlphabet[mod.Int64()])
+	}
+	for i := 0; i < leadingZeros; i++ {
+		out = append(out, base58btcAlphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58btcDecode decodes a base58btc string back to bytes.
+func base58btcDecode(s string) ([]byte, error) {
+	leadingZeros := 0
+	for _, c := range s {
+		if byte(c) != base58btcAlphabet[0] {
+			break
+		}
+		leadingZeros++
+	}
+
+	n := new(big.Int)
+	base := big.NewInt(58)
+	for _, c := range s {
+		idx := strings.IndexRune(base58btcAlphabet, c)
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid base58btc character: %q", c)
+		}
+		n.Mul(n, base)
+		n.Add(n, big.NewInt(int64(idx)))
+	}
+
+	body := n.Bytes()
+	out := make([]byte, leadingZeros+len(body))
+	copy(out[leadingZeros:], body)
+	return out, nil
+}
@@ -0,0 +1,80 @@
+package didkey
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/actoris/actoris/services/identity-cloud/internal/domain"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		name      string
+		keyType   domain.KeyType
+		publicKey []byte
+	}{
+		{"ed25519", domain.KeyTypeEd25519, bytes.Repeat([]byte{0x01}, 32)},
+		{"secp256k1", domain.KeyTypeSecp256k1, bytes.Repeat([]byte{0x02}, 33)},
+		{"p256", domain.KeyTypeP256, bytes.Repeat([]byte{0x03}, 33)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			did, err := Encode(tt.keyType, tt.publicKey)
+			if err != nil {
+				t.Fatalf("Encode: %v", err)
+			}
+
+			keyType, publicKey, err := Decode(did)
+			if err != nil {
+				t.Fatalf("Decode(%q): %v", did, err)
+			}
+			if keyType != tt.keyType {
+				t.Errorf("keyType = %v, want %v", keyType, tt.keyType)
+			}
+			if !bytes.Equal(publicKey, tt.publicKey) {
+				t.Errorf("publicKey = %x, want %x", publicKey, tt.publicKey)
+			}
+		})
+	}
+}
+
+// TestP256MulticodecPrefix pins the P-256 multicodec bytes to the correct
+// LEB128 varint encoding of 0x1200 -- {0x80, 0x24} -- rather than the raw
+// big-endian bytes of 0x1200, which no spec-compliant resolver emits or
+// accepts.
+func TestP256MulticodecPrefix(t *testing.T) {
+	want := []byte{0x80, 0x24}
+	got := multicodecPrefix[domain.KeyTypeP256]
+	if !bytes.Equal(got, want) {
+		t.Errorf("P-256 multicodec prefix = %x, want %x", got, want)
+	}
+}
+
+// TestDecodeExternalP256DIDKey decodes a did:key produced by an
+// independent, spec-compliant encoder (rather than this package's own
+// Encode), so a prefix bug that happens to round-trip through Encode/Decode
+// symmetrically wouldn't be caught by TestEncodeDecodeRoundTrip alone.
+func TestDecodeExternalP256DIDKey(t *testing.T) {
+	publicKey := bytes.Repeat([]byte{0x04}, 33)
+	encoded := append([]byte{0x80, 0x24}, publicKey...)
+	did := "did:key:z" + base58btcEncode(encoded)
+
+	keyType, decodedKey, err := Decode(did)
+	if err != nil {
+		t.Fatalf("Decode(%q): %v", did, err)
+	}
+	if keyType != domain.KeyTypeP256 {
+		t.Errorf("keyType = %v, want %v", keyType, domain.KeyTypeP256)
+	}
+	if !bytes.Equal(decodedKey, publicKey) {
+		t.Errorf("publicKey = %x, want %x", decodedKey, publicKey)
+	}
+}
+
+func TestDecodeRejectsUnrecognizedPrefix(t *testing.T) {
+	did := "did:key:z" + base58btcEncode([]byte{0xff, 0xff, 0x01, 0x02, 0x03})
+	if _, _, err := Decode(did); err == nil {
+		t.Error("expected an error for an unrecognized multicodec prefix, got nil")
+	}
+}
@@ -0,0 +1,59 @@
+package domain
+
+import "time"
+
+// CredentialFormat selects how a VerifiableCredential is serialized for
+// transport.
+type CredentialFormat int
+
+const (
+	CredentialFormatUnspecified CredentialFormat = iota
+	CredentialFormatJWT
+	CredentialFormatJSONLD
+)
+
+func (f CredentialFormat) String() string {
+	switch f {
+	case CredentialFormatJWT:
+		return "vc+jwt"
+	case CredentialFormatJSONLD:
+		return "ldp"
+	default:
+		return "unspecified"
+	}
+}
+
+// Credential is a W3C Verifiable Credential, trimmed to the fields this
+// service issues and verifies. StatusListIndex/StatusListCredential identify
+// its slot in the issuer's status-list revocation registry (see
+// repository.AllocateStatusListIndex).
+type Credential struct {
+	ID                   string
+	Issuer               string
+	Subject              string
+	Claims               map[string]any
+	IssuanceDate         time.Time
+	ExpirationDate       time.Time
+	StatusListIndex      uint32
+	StatusListCredential string
+	Format               CredentialFormat
+	Proof                []byte
+}
+
+// IsExpired reports whether the credential's expiration date has passed as
+// of now. A zero ExpirationDate means the credential never expires.
+func (c *Credential) IsExpired(now time.Time) bool {
+	return !c.ExpirationDate.IsZero() && now.After(c.ExpirationDate)
+}
+
+// Presentation is a W3C Verifiable Presentation wrapping one or more
+// credentials with a holder-signed proof binding it to a verifier's
+// challenge and domain, preventing the presentation from being replayed
+// against a different verifier.
+type Presentation struct {
+	Holder      string
+	Credentials []Credential
+	Challenge   string
+	Domain      string
+	Proof       []byte
+}
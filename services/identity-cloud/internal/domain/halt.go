@@ -0,0 +1,100 @@
+package domain
+
+import "time"
+
+// HaltScope identifies what a HaltEntry freezes.
+type HaltScope int
+
+const (
+	HaltScopeUnspecified HaltScope = iota
+	HaltScopeGlobal                // freezes every wallet-mutating RPC
+	HaltScopeDID                   // freezes a single identity's wallet
+	HaltScopeEntityType            // freezes every identity of an entity type
+)
+
+func (s HaltScope) String() string {
+	switch s {
+	case HaltScopeGlobal:
+		return "global"
+	case HaltScopeDID:
+		return "did"
+	case HaltScopeEntityType:
+		return "entity_type"
+	default:
+		return "unspecified"
+	}
+}
+
+// HaltStatus tracks a halt proposal through its governance lifecycle.
+type HaltStatus int
+
+const (
+	HaltStatusUnspecified HaltStatus = iota
+	HaltStatusPending                 // proposed, still short of quorum
+	HaltStatusActive                  // quorum reached, enforced once effective_at passes
+	HaltStatusCancelled
+)
+
+func (s HaltStatus) String() string {
+	switch s {
+	case HaltStatusPending:
+		return "pending"
+	case HaltStatusActive:
+		return "active"
+	case HaltStatusCancelled:
+		return "cancelled"
+	default:
+		return "unspecified"
+	}
+}
+
+// HaltSignature is one governance DID's vote for a halt proposal, verified
+// against that DID's public key before being counted toward quorum.
+type HaltSignature struct {
+	SignerDID string    `json:"signer_did"`
+	Signature []byte    `json:"signature"`
+	SignedAt  time.Time `json:"signed_at"`
+}
+
+// HaltEntry is a governance-proposed freeze on wallet mutations, borrowed
+// from the scheduled-halt pattern consensus chains use to stop block
+// production at a target height: N-of-M designated governance DIDs sign off,
+// and once quorum is reached the halt is enforced from EffectiveAt onward
+// until explicitly cancelled.
+type HaltEntry struct {
+	ID          string          `json:"id"`
+	Scope       HaltScope       `json:"scope"`
+	Target      string          `json:"target,omitempty"` // DID or entity_type string; empty for HaltScopeGlobal
+	EffectiveAt time.Time       `json:"effective_at"`
+	Reason      string          `json:"reason"`
+	ProposedBy  string          `json:"proposed_by"`
+	Quorum      int             `json:"quorum"`
+	Signatures  []HaltSignature `json:"signatures"`
+	Status      HaltStatus      `json:"status"`
+	CreatedAt   time.Time       `json:"created_at"`
+	CancelledAt *time.Time      `json:"cancelled_at,omitempty"`
+}
+
+// HasQuorum reports whether enough governance DIDs have signed off.
+func (h *HaltEntry) HasQuorum() bool {
+	return len(h.Signatures) >= h.Quorum
+}
+
+// Applies reports whether this halt currently freezes wallet mutations for
+// did/entityType: it must have quorum, not be cancelled, and its
+// EffectiveAt must have passed.
+func (h *HaltEntry) Applies(did string, entityType EntityType, now time.Time) bool {
+	if h.Status == HaltStatusCancelled || !h.HasQuorum() || now.Before(h.EffectiveAt) {
+		return false
+	}
+	switch h.Scope {
+	case HaltScopeGlobal:
+		return true
+	case HaltScopeDID:
+		return h.Target == did
+	case HaltScopeEntityType:
+		return h.Target == entityType.String()
+	default:
+		return false
+	}
+}
@@ -0,0 +1,123 @@
+package domain
+
+import (
+	"math"
+	"sort"
+)
+
+// GenerationCohort is one generation's worth of tau values along the path
+// from an agent up to its root: the ancestor's own tau plus the tau of every
+// sibling that shares that ancestor's parent. Depth 1 is the immediate
+// parent, depth 2 the grandparent, and so on.
+type GenerationCohort struct {
+	Depth       int       `json:"depth"`
+	AncestorTau float64   `json:"ancestor_tau"`
+	SiblingTaus []float64 `json:"sibling_taus"` // includes AncestorTau
+}
+
+// GenerationWeight records how much a single generation contributed to the
+// final inherited tau, for display in an InheritanceExplanation.
+type GenerationWeight struct {
+	Depth      int     `json:"depth"`
+	Weight     float64 `json:"weight"`      // normalized, sums to 1 across all generations
+	TrimmedTau float64 `json:"trimmed_tau"` // the cohort's trimmed (or plain, or fallback) mean
+	CohortSize int     `json:"cohort_size"`
+	WasTrimmed bool    `json:"was_trimmed"` // false when cohort fell back to the ancestor's own tau
+}
+
+// InheritanceExplanation is the auditable breakdown behind an
+// AggregateInheritedTau result: every generation's weight and the trimmed
+// tau it contributed, plus the final capped/floored value.
+type InheritanceExplanation struct {
+	Generations []GenerationWeight `json:"generations"`
+	RawTau      float64            `json:"raw_tau"` // before the MaxInheritedTau/parent/MinInheritedTau clamp
+	FinalTau    float64            `json:"final_tau"`
+}
+
+// trimmedMean drops the top and bottom 25% of a sorted copy of values and
+// averages what remains. Callers are expected to have already checked the
+// cohort meets minCohortSize before calling this.
+func trimmedMean(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	trim := len(sorted) / 4
+	kept := sorted[trim : len(sorted)-trim]
+	if len(kept) == 0 {
+		kept = sorted
+	}
+	sum := 0.0
+	for _, v := range kept {
+		sum += v
+	}
+	return sum / float64(len(kept))
+}
+
+// AggregateInheritedTau computes a Byzantine-fault-tolerant inherited tau for
+// a child spawned under parentTau, from the chain of ancestor cohorts
+// (ordered nearest-ancestor-first, i.e. cohorts[0] is the parent's
+// generation). At each generation, a cohort of at least minCohortSize
+// siblings has its top and bottom quartile trimmed before averaging, which
+// bounds how much any single compromised sibling can pull the result;
+// smaller cohorts fall back to the ancestor's own tau for that generation.
+// Generations are combined with a weight that decays geometrically
+// (InheritedTrust^depth) and is further discounted by DepthPenalty, then
+// normalized across generations to sum to 1. The result is capped at
+// min(parentTau, MaxInheritedTau) and floored at MinInheritedTau.
+func AggregateInheritedTau(cohorts []GenerationCohort, parentTau float64, minCohortSize int) (float64, InheritanceExplanation) {
+	if len(cohorts) == 0 {
+		inherited := parentTau * InheritedTrust
+		if inherited < MinInheritedTau {
+			inherited = MinInheritedTau
+		}
+		return inherited, InheritanceExplanation{RawTau: inherited, FinalTau: inherited}
+	}
+
+	rawWeights := make([]float64, len(cohorts))
+	weightSum := 0.0
+	for i, c := range cohorts {
+		k := float64(c.Depth)
+		w := math.Pow(InheritedTrust, k) / (1 + DepthPenalty*k)
+		rawWeights[i] = w
+		weightSum += w
+	}
+
+	explanation := InheritanceExplanation{Generations: make([]GenerationWeight, len(cohorts))}
+	raw := 0.0
+	for i, c := range cohorts {
+		normalizedWeight := rawWeights[i] / weightSum
+
+		var tau float64
+		trimmed := false
+		if len(c.SiblingTaus) >= minCohortSize {
+			tau = trimmedMean(c.SiblingTaus)
+			trimmed = true
+		} else {
+			tau = c.AncestorTau
+		}
+
+		raw += normalizedWeight * tau
+		explanation.Generations[i] = GenerationWeight{
+			Depth:      c.Depth,
+			Weight:     normalizedWeight,
+			TrimmedTau: tau,
+			CohortSize: len(c.SiblingTaus),
+			WasTrimmed: trimmed,
+		}
+	}
+
+	ceiling := parentTau
+	if MaxInheritedTau < ceiling {
+		ceiling = MaxInheritedTau
+	}
+	final := raw
+	if final > ceiling {
+		final = ceiling
+	}
+	if final < MinInheritedTau {
+		final = MinInheritedTau
+	}
+
+	explanation.RawTau = raw
+	explanation.FinalTau = final
+	return final, explanation
+}
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTrimmedMean(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{
+			name:   "drops top and bottom quartile",
+			values: []float64{0.0, 0.5, 0.5, 0.5, 1.0},
+			want:   0.5,
+		},
+		{
+			name:   "outlier excluded once trimmed",
+			values: []float64{0.1, 0.2, 0.3, 0.4, 100.0},
+			want:   0.3,
+		},
+		{
+			name:   "too small to trim falls back to plain mean",
+			values: []float64{0.2, 0.8},
+			want:   0.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trimmedMean(tt.values)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("trimmedMean(%v) = %v, want %v", tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrimmedMeanDoesNotMutateInput(t *testing.T) {
+	values := []float64{0.9, 0.1, 0.5, 0.3, 0.7}
+	original := append([]float64(nil), values...)
+
+	trimmedMean(values)
+
+	for i := range values {
+		if values[i] != original[i] {
+			t.Errorf("trimmedMean mutated its input: got %v, want %v", values, original)
+		}
+	}
+}
+
+func TestAggregateInheritedTauNoCohorts(t *testing.T) {
+	parentTau := 0.6
+	tau, explanation := AggregateInheritedTau(nil, parentTau, 4)
+
+	want := parentTau * InheritedTrust
+	if tau != want {
+		t.Errorf("tau = %v, want %v", tau, want)
+	}
+	if explanation.RawTau != tau || explanation.FinalTau != tau {
+		t.Errorf("explanation = %+v, want RawTau and FinalTau both %v", explanation, tau)
+	}
+	if len(explanation.Generations) != 0 {
+		t.Errorf("expected no per-generation breakdown, got %v", explanation.Generations)
+	}
+}
+
+func TestAggregateInheritedTauFloorsAtMinimum(t *testing.T) {
+	cohorts := []GenerationCohort{
+		{Depth: 1, AncestorTau: 0.0, SiblingTaus: []float64{0.0, 0.0}},
+	}
+
+	tau, explanation := AggregateInheritedTau(cohorts, 0.0, 4)
+
+	if tau != MinInheritedTau {
+		t.Errorf("tau = %v, want floor %v", tau, MinInheritedTau)
+	}
+	if explanation.FinalTau != MinInheritedTau {
+		t.Errorf("explanation.FinalTau = %v, want %v", explanation.FinalTau, MinInheritedTau)
+	}
+}
+
+func TestAggregateInheritedTauCapsAtParentAndMax(t *testing.T) {
+	cohorts := []GenerationCohort{
+		{Depth: 1, AncestorTau: 1.0, SiblingTaus: []float64{1.0, 1.0}},
+	}
+
+	// parentTau below MaxInheritedTau: the parent's own tau is the tighter cap.
+	tau, _ := AggregateInheritedTau(cohorts, 0.5, 4)
+	if tau > 0.5 {
+		t.Errorf("tau = %v, want capped at parentTau 0.5", tau)
+	}
+
+	// parentTau above MaxInheritedTau: MaxInheritedTau is the tighter cap.
+	tau, _ = AggregateInheritedTau(cohorts, 0.95, 4)
+	if tau > MaxInheritedTau {
+		t.Errorf("tau = %v, want capped at MaxInheritedTau %v", tau, MaxInheritedTau)
+	}
+}
+
+func TestAggregateInheritedTauSmallCohortFallsBackToAncestor(t *testing.T) {
+	cohorts := []GenerationCohort{
+		{Depth: 1, AncestorTau: 0.4, SiblingTaus: []float64{0.4, 0.9}}, // below minCohortSize of 4
+	}
+
+	_, explanation := AggregateInheritedTau(cohorts, 0.6, 4)
+
+	if len(explanation.Generations) != 1 {
+		t.Fatalf("expected one generation, got %d", len(explanation.Generations))
+	}
+	gen := explanation.Generations[0]
+	if gen.WasTrimmed {
+		t.Errorf("expected WasTrimmed=false for an under-sized cohort, got true")
+	}
+	if gen.TrimmedTau != 0.4 {
+		t.Errorf("TrimmedTau = %v, want ancestor's own tau 0.4", gen.TrimmedTau)
+	}
+}
+
+func TestAggregateInheritedTauWeightsSumToOne(t *testing.T) {
+	cohorts := []GenerationCohort{
+		{Depth: 1, AncestorTau: 0.5, SiblingTaus: []float64{0.5, 0.5, 0.5, 0.5}},
+		{Depth: 2, AncestorTau: 0.4, SiblingTaus: []float64{0.4, 0.4, 0.4, 0.4}},
+		{Depth: 3, AncestorTau: 0.3, SiblingTaus: []float64{0.3, 0.3, 0.3, 0.3}},
+	}
+
+	_, explanation := AggregateInheritedTau(cohorts, 0.6, 4)
+
+	sum := 0.0
+	for _, gen := range explanation.Generations {
+		sum += gen.Weight
+	}
+	if math.Abs(sum-1.0) > 1e-9 {
+		t.Errorf("generation weights sum to %v, want 1.0", sum)
+	}
+
+	// Weight should strictly decrease with depth under geometric decay plus
+	// DepthPenalty discounting.
+	for i := 1; i < len(explanation.Generations); i++ {
+		if explanation.Generations[i].Weight >= explanation.Generations[i-1].Weight {
+			t.Errorf("expected weight to decay with depth, gen %d weight %v >= gen %d weight %v",
+				i, explanation.Generations[i].Weight, i-1, explanation.Generations[i-1].Weight)
+		}
+	}
+}
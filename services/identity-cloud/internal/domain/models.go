@@ -38,6 +38,37 @@ type UnifiedID struct {
 	ParentDID  *string    `json:"parent_did,omitempty"`
 	CreatedAt  time.Time  `json:"created_at"`
 	PublicKey  []byte     `json:"public_key"`
+	KeyType    KeyType    `json:"key_type"`
+	// DerivationIndex is the monotonic counter (scoped to ParentDID) used
+	// alongside the master seed to deterministically derive this identity's
+	// keypair; see internal/keys. Only this index is persisted, never the
+	// private key.
+	DerivationIndex uint64 `json:"derivation_index"`
+}
+
+// KeyType identifies the cryptographic key type backing an identity's
+// public key, used to pick the did:key multicodec prefix and the right
+// signature algorithm for verification.
+type KeyType int
+
+const (
+	KeyTypeUnspecified KeyType = iota
+	KeyTypeEd25519
+	KeyTypeSecp256k1
+	KeyTypeP256
+)
+
+func (k KeyType) String() string {
+	switch k {
+	case KeyTypeEd25519:
+		return "Ed25519"
+	case KeyTypeSecp256k1:
+		return "secp256k1"
+	case KeyTypeP256:
+		return "P-256"
+	default:
+		return "unspecified"
+	}
 }
 
 // TrustComponents holds the breakdown of trust score components
@@ -60,12 +91,25 @@ type TrustScore struct {
 
 // Constants for trust score calculation
 const (
-	MaxScore         = 1000
-	MaxDiscountRate  = 0.20 // 20% maximum discount for high trust
-	GraceEpochs      = 10
-	InheritedTrust   = 0.30 // Spawned agents inherit 30% of parent trust
-	DecayFactor      = 0.005
-	MinInheritedTau  = 0.10
+	MaxScore        = 1000
+	MaxDiscountRate = 0.20 // 20% maximum discount for high trust
+	GraceEpochs     = 10
+	InheritedTrust  = 0.30 // Spawned agents inherit 30% of parent trust
+	DecayFactor     = 0.005
+	MinInheritedTau = 0.10
+	// MaxInheritedTau bounds lineage-weighted inheritance on top of the
+	// per-ancestor InheritedTauForChild cap so a long, high-trust chain
+	// can't accumulate unbounded influence.
+	MaxInheritedTau = 0.70
+	// DepthPenalty further discounts each generation's weight beyond the
+	// geometric InheritedTrust^depth decay, so distant ancestors count for
+	// even less than pure geometric decay alone would give them.
+	DepthPenalty = 0.15
+	// DefaultMinCohortSize is the minimum number of siblings (inclusive of
+	// the ancestor itself) a generation needs before its trimmed mean is
+	// considered meaningful; smaller cohorts fall back to the ancestor's own
+	// tau for that generation.
+	DefaultMinCohortSize = 4
 )
 
 // Tau returns the normalized trust score (0.0 to 1.0)
@@ -97,6 +141,19 @@ func (t *TrustScore) IsLowTrust() bool {
 	return t.Score < 500
 }
 
+// ClaimableTrustDelta is the TrustScore state that would result from folding
+// in every unclaimed VerificationOutcome recorded for an identity. It is a
+// projection, not a mutation: GetClaimableTrustDelta computes it from the
+// append-only outcome log, and ClaimTrustDelta is what actually writes it
+// back to the TrustScore node.
+type ClaimableTrustDelta struct {
+	VerificationScore uint32 `json:"verification_score"`
+	SLAScore          uint32 `json:"sla_score"`
+	Score             uint32 `json:"score"`
+	VerifiedOutcomes  uint64 `json:"verified_outcomes"`
+	PendingOutcomes   uint64 `json:"pending_outcomes"` // unclaimed outcomes folded into the fields above
+}
+
 // InheritedTauForChild calculates the trust score a child entity inherits
 func (t *TrustScore) InheritedTauForChild() float64 {
 	inherited := t.Tau() * InheritedTrust
@@ -153,6 +210,27 @@ const (
 	RelationshipTrusted   = "TRUSTED"   // Trust relationship
 )
 
+// TransferKind constants identify the wallet operation that produced an
+// HCTransfer ledger entry
+const (
+	TransferKindLock    = "lock"
+	TransferKindRelease = "release"
+	TransferKindRefund  = "refund"
+	TransferKindCredit  = "credit"
+)
+
+// Transfer is one append-only entry in a wallet's HC ledger, recording a
+// single lock/release/refund/credit mutation for audit and replay purposes
+type Transfer struct {
+	ID         uuid.UUID       `json:"id"`
+	FromDID    string          `json:"from_did,omitempty"`
+	ToDID      string          `json:"to_did,omitempty"`
+	Amount     decimal.Decimal `json:"amount"`
+	Kind       string          `json:"kind"`
+	BlockIndex uint64          `json:"block_index"` // monotonic batch number, currently the wallet version after the mutation
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
 // AgentLineage tracks the spawn history of an agent
 type AgentLineage struct {
 	AgentDID   string     `json:"agent_did"`
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEventType identifies the kind of change a WebhookEvent represents.
+// It mirrors the EventTopic split the service package's in-process EventBus
+// uses for live gRPC watchers, but covers the broader set of changes
+// external integrations (pricing, fraud, billing) need a push-based
+// notification for instead of polling GetTrustScore/GetHCWallet.
+type WebhookEventType string
+
+const (
+	WebhookEventIdentityCreated              WebhookEventType = "identity.created"
+	WebhookEventIdentitySpawned              WebhookEventType = "identity.spawned"
+	WebhookEventTrustScoreThresholdCrossed   WebhookEventType = "trust_score.threshold_crossed"
+	WebhookEventVerificationOutcomeRecorded  WebhookEventType = "verification.outcome_recorded"
+	WebhookEventWalletLocked                 WebhookEventType = "wallet.locked"
+	WebhookEventWalletReleased               WebhookEventType = "wallet.released"
+	WebhookEventWalletRefunded               WebhookEventType = "wallet.refunded"
+	WebhookEventWalletCredited               WebhookEventType = "wallet.credited"
+	WebhookEventWalletExpired                WebhookEventType = "wallet.expired"
+)
+
+// WebhookSubscription is a downstream integration's registration for a
+// filtered slice of webhook events, persisted as a (:Webhook) node. Secret
+// is the HMAC key deliveries are signed with; it is never serialized back
+// out to API responses.
+type WebhookSubscription struct {
+	ID         uuid.UUID          `json:"id"`
+	URL        string             `json:"url"`
+	Secret     string             `json:"-"`
+	EventTypes []WebhookEventType `json:"event_types"`
+	// DIDFilter, if set, restricts this subscription to events about one
+	// DID; nil means all DIDs.
+	DIDFilter *string   `json:"did_filter,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Matches reports whether sub should receive event: event.Type must be in
+// sub's filter list (an empty list means "every event type"), and if sub
+// scopes itself to one DID, event.DID must match it.
+func (sub *WebhookSubscription) Matches(event WebhookEvent) bool {
+	if sub.DIDFilter != nil && *sub.DIDFilter != event.DID {
+		return false
+	}
+	if len(sub.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range sub.EventTypes {
+		if t == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookEvent is one fact pushed to every matching subscription: an
+// identity, trust score, or wallet state transition. Data is the relevant
+// domain object's state after the transition.
+type WebhookEvent struct {
+	ID         uuid.UUID        `json:"id"`
+	Type       WebhookEventType `json:"type"`
+	DID        string           `json:"did"`
+	OccurredAt time.Time        `json:"occurred_at"`
+	Data       any              `json:"data"`
+}
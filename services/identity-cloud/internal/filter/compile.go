@@ -0,0 +1,264 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// kind identifies how a field's literal values should be coerced before
+// being bound as Cypher query parameters.
+type kind int
+
+const (
+	kindString kind = iota
+	kindInt
+	kindFloat
+	kindDateTime
+)
+
+// fieldSpec describes one filterable field: the Cypher expression it reads
+// from, whether it's backed by a Neo4j index (see initializeSchema in
+// cmd/server/main.go), and how its literal values are typed.
+type fieldSpec struct {
+	cypher  string
+	indexed bool
+	kind    kind
+}
+
+// fields is the allowlist of predicates list RPCs may filter on. Any field
+// not listed here is rejected by Compile, so a filter expression can never
+// reach an arbitrary node property.
+var fields = map[string]fieldSpec{
+	"entity_type":                          {cypher: "child.entity_type", indexed: true, kind: kindString},
+	"created_at":                           {cypher: "child.created_at", indexed: false, kind: kindDateTime},
+	"parent_did":                           {cypher: "child.parent_did", indexed: true, kind: kindString},
+	"trust.score":                          {cypher: "trust.score", indexed: true, kind: kindInt},
+	"trust.components.verification_score":  {cypher: "trust.verification_score", indexed: false, kind: kindInt},
+	"trust.components.dispute_penalty":     {cypher: "trust.dispute_penalty", indexed: false, kind: kindInt},
+	"trust.components.sla_score":           {cypher: "trust.sla_score", indexed: false, kind: kindInt},
+	"trust.components.network_score":       {cypher: "trust.network_score", indexed: false, kind: kindInt},
+	"wallet.available":                     {cypher: "toFloat(wallet.available) / 1e18", indexed: false, kind: kindFloat},
+	"wallet.expires_at":                    {cypher: "wallet.expires_at", indexed: true, kind: kindDateTime},
+}
+
+var cypherOps = map[string]string{
+	"==": "=",
+	"!=": "<>",
+	"<":  "<",
+	"<=": "<=",
+	">":  ">",
+	">=": ">=",
+}
+
+// Compiled is a filter expression compiled to a Cypher boolean expression,
+// ready to be spliced into a WHERE clause alongside its bound parameters.
+type Compiled struct {
+	Cypher string
+	Params map[string]any
+}
+
+// Compile validates expr against the field allowlist and the indexed-field
+// boundedness rule (see isBounded), then compiles it to a parameterized
+// Cypher boolean expression. The query this is spliced into must bind
+// `child`, `trust`, and `wallet` to the Identity, TrustScore, and HCWallet
+// nodes respectively.
+func Compile(expr Expr) (*Compiled, error) {
+	if err := validateFields(expr); err != nil {
+		return nil, err
+	}
+	if referencesNonIndexedField(expr) && !isBounded(expr) {
+		return nil, fmt.Errorf("filter: expression references a non-indexed field without a bounded indexed-field predicate in every branch; add a predicate on entity_type, parent_did, trust.score, or wallet.expires_at")
+	}
+
+	c := &compiler{params: make(map[string]any)}
+	cypher, err := c.compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &Compiled{Cypher: cypher, Params: c.params}, nil
+}
+
+type compiler struct {
+	params map[string]any
+	n      int
+}
+
+func (c *compiler) param(v any) string {
+	name := fmt.Sprintf("filter_p%d", c.n)
+	c.n++
+	c.params[name] = v
+	return "$" + name
+}
+
+func (c *compiler) compile(expr Expr) (string, error) {
+	switch e := expr.(type) {
+	case And:
+		left, err := c.compile(e.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := c.compile(e.Right)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s AND %s)", left, right), nil
+
+	case Or:
+		left, err := c.compile(e.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := c.compile(e.Right)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s OR %s)", left, right), nil
+
+	case Not:
+		inner, err := c.compile(e.Expr)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("NOT (%s)", inner), nil
+
+	case Comparison:
+		return c.compileComparison(e)
+
+	default:
+		return "", fmt.Errorf("filter: unknown expression node %T", expr)
+	}
+}
+
+func (c *compiler) compileComparison(cmp Comparison) (string, error) {
+	spec, ok := fields[cmp.Field]
+	if !ok {
+		return "", fmt.Errorf("filter: unknown field %q", cmp.Field)
+	}
+
+	switch cmp.Op {
+	case "in":
+		values := make([]any, len(cmp.Value.List))
+		for i, lit := range cmp.Value.List {
+			v, err := coerce(spec.kind, lit)
+			if err != nil {
+				return "", fmt.Errorf("filter: field %q: %w", cmp.Field, err)
+			}
+			values[i] = v
+		}
+		return fmt.Sprintf("%s IN %s", spec.cypher, c.param(values)), nil
+
+	case "matches":
+		if spec.kind != kindString {
+			return "", fmt.Errorf("filter: field %q does not support \"matches\"", cmp.Field)
+		}
+		return fmt.Sprintf("%s =~ %s", spec.cypher, c.param(cmp.Value.Scalar)), nil
+
+	default:
+		op, ok := cypherOps[cmp.Op]
+		if !ok {
+			return "", fmt.Errorf("filter: unsupported operator %q", cmp.Op)
+		}
+		v, err := coerce(spec.kind, cmp.Value.Scalar)
+		if err != nil {
+			return "", fmt.Errorf("filter: field %q: %w", cmp.Field, err)
+		}
+		cypherValue := c.param(v)
+		if spec.kind == kindDateTime {
+			cypherValue = fmt.Sprintf("datetime(%s)", cypherValue)
+		}
+		return fmt.Sprintf("%s %s %s", spec.cypher, op, cypherValue), nil
+	}
+}
+
+// coerce converts a literal's text into the Go value matching kind, so it's
+// bound to the Cypher query as the right type rather than as a string.
+func coerce(k kind, literal string) (any, error) {
+	switch k {
+	case kindString:
+		return literal, nil
+	case kindInt:
+		n, err := strconv.ParseInt(literal, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected an integer, got %q", literal)
+		}
+		return n, nil
+	case kindFloat:
+		f, err := strconv.ParseFloat(literal, 64)
+		if err != nil {
+			return nil, fmt.Errorf("expected a number, got %q", literal)
+		}
+		return f, nil
+	case kindDateTime:
+		if _, err := time.Parse(time.RFC3339, literal); err != nil {
+			return nil, fmt.Errorf("expected an RFC3339 timestamp, got %q", literal)
+		}
+		return literal, nil
+	default:
+		return nil, fmt.Errorf("unknown field kind")
+	}
+}
+
+// validateFields rejects any expression referencing a field outside the
+// fields allowlist, so a filter can never reach an arbitrary node property.
+func validateFields(expr Expr) error {
+	switch e := expr.(type) {
+	case And:
+		if err := validateFields(e.Left); err != nil {
+			return err
+		}
+		return validateFields(e.Right)
+	case Or:
+		if err := validateFields(e.Left); err != nil {
+			return err
+		}
+		return validateFields(e.Right)
+	case Not:
+		return validateFields(e.Expr)
+	case Comparison:
+		if _, ok := fields[e.Field]; !ok {
+			return fmt.Errorf("filter: unknown field %q", e.Field)
+		}
+		return nil
+	default:
+		return fmt.Errorf("filter: unknown expression node %T", expr)
+	}
+}
+
+// referencesNonIndexedField reports whether expr mentions any field not
+// backed by a Neo4j index anywhere in the tree.
+func referencesNonIndexedField(expr Expr) bool {
+	switch e := expr.(type) {
+	case And:
+		return referencesNonIndexedField(e.Left) || referencesNonIndexedField(e.Right)
+	case Or:
+		return referencesNonIndexedField(e.Left) || referencesNonIndexedField(e.Right)
+	case Not:
+		return referencesNonIndexedField(e.Expr)
+	case Comparison:
+		return !fields[e.Field].indexed
+	default:
+		return false
+	}
+}
+
+// isBounded reports whether every possible evaluation path through expr
+// passes through at least one predicate on an indexed field, so the
+// compiled query always has an index to use rather than falling back to a
+// table scan. AND only needs one side bounded; OR needs both sides bounded,
+// since either branch can be the one that executes; NOT can't bound a
+// query, since excluding one value doesn't narrow the scan.
+func isBounded(expr Expr) bool {
+	switch e := expr.(type) {
+	case And:
+		return isBounded(e.Left) || isBounded(e.Right)
+	case Or:
+		return isBounded(e.Left) && isBounded(e.Right)
+	case Not:
+		return false
+	case Comparison:
+		return fields[e.Field].indexed
+	default:
+		return false
+	}
+}
@@ -0,0 +1,42 @@
+package filter
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Cursor is the decoded form of a list RPC's opaque page_token: the sort
+// key and DID of the last row returned, used as a keyset bound for the next
+// page instead of an OFFSET (which degrades as the offset grows and can
+// skip or repeat rows if the underlying data changes between pages).
+type Cursor struct {
+	SortKey string `json:"sort_key"`
+	DID     string `json:"did"`
+}
+
+// EncodeCursor produces an opaque page_token for the last row of a page.
+func EncodeCursor(sortKey, did string) (string, error) {
+	data, err := json.Marshal(Cursor{SortKey: sortKey, DID: did})
+	if err != nil {
+		return "", fmt.Errorf("filter: encoding cursor: %w", err)
+	}
+	return base64.URLEncoding.EncodeToString(data), nil
+}
+
+// DecodeCursor parses a page_token produced by EncodeCursor. An empty token
+// decodes to the zero Cursor, representing the first page.
+func DecodeCursor(token string) (Cursor, error) {
+	if token == "" {
+		return Cursor{}, nil
+	}
+	data, err := base64.URLEncoding.DecodeString(token)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("filter: invalid page_token: %w", err)
+	}
+	var c Cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Cursor{}, fmt.Errorf("filter: invalid page_token: %w", err)
+	}
+	return c, nil
+}
@@ -0,0 +1,391 @@
+// Package filter implements a small expression language for list RPCs:
+// boolean predicates over a fixed set of identity, trust, and wallet
+// fields, parsed to an AST and compiled (see compile.go) to a parameterized
+// Cypher WHERE clause so filtering happens inside Neo4j instead of in Go.
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Expr is a node in a parsed filter expression's AST.
+type Expr interface {
+	isExpr()
+}
+
+// Comparison tests Field against Value using Op.
+type Comparison struct {
+	Field string
+	Op    string // "==", "!=", "<", "<=", ">", ">=", "in", "matches"
+	Value Value
+}
+
+func (Comparison) isExpr() {}
+
+// And is the logical conjunction of Left and Right.
+type And struct {
+	Left, Right Expr
+}
+
+func (And) isExpr() {}
+
+// Or is the logical disjunction of Left and Right.
+type Or struct {
+	Left, Right Expr
+}
+
+func (Or) isExpr() {}
+
+// Not negates Expr.
+type Not struct {
+	Expr Expr
+}
+
+func (Not) isExpr() {}
+
+// Value is a comparison operand. List is only populated for the "in"
+// operator; every other operator uses Scalar. Values are always carried as
+// their literal text, whether they were written quoted or bare — the field
+// they're compared against (see compile.go) determines how they're coerced.
+type Value struct {
+	Scalar string
+	List   []string
+}
+
+// Parse parses a filter expression, e.g.
+// `trust.score >= 700 and entity_type in ("agent","sub_agent") and wallet.available > "10.0"`.
+func Parse(input string) (Expr, error) {
+	p := &parser{lex: newLexer(input)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("filter: unexpected token %q", p.tok.text)
+	}
+	return expr, nil
+}
+
+// parser is a recursive-descent parser over the grammar:
+//
+//	expr       := orExpr
+//	orExpr     := andExpr ( "or" andExpr )*
+//	andExpr    := notExpr ( "and" notExpr )*
+//	notExpr    := "not" notExpr | primary
+//	primary    := "(" orExpr ")" | comparison
+//	comparison := field operator value
+//	field      := IDENT ( "." IDENT )*
+//	operator   := "==" | "!=" | "<=" | ">=" | "<" | ">" | "in" | "matches"
+//	value      := STRING | NUMBER | IDENT | "(" literal ( "," literal )* ")"
+//	literal    := STRING | NUMBER
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokOr {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tokAnd {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.tok.kind == tokNot {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	if p.tok.kind == tokLParen {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokRParen {
+			return nil, fmt.Errorf("filter: expected ')', got %q", p.tok.text)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		return expr, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	field, err := p.parseField()
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := p.parseOperator()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if op != "in" && value.List != nil {
+		return nil, fmt.Errorf("filter: %q does not accept a list value", op)
+	}
+
+	return Comparison{Field: field, Op: op, Value: value}, nil
+}
+
+func (p *parser) parseField() (string, error) {
+	if p.tok.kind != tokIdent {
+		return "", fmt.Errorf("filter: expected a field name, got %q", p.tok.text)
+	}
+	field := p.tok.text
+	if err := p.advance(); err != nil {
+		return "", err
+	}
+	return field, nil
+}
+
+func (p *parser) parseOperator() (string, error) {
+	switch p.tok.kind {
+	case tokOp, tokIn, tokMatches:
+		op := p.tok.text
+		if err := p.advance(); err != nil {
+			return "", err
+		}
+		return op, nil
+	default:
+		return "", fmt.Errorf("filter: expected an operator, got %q", p.tok.text)
+	}
+}
+
+func (p *parser) parseValue() (Value, error) {
+	switch p.tok.kind {
+	case tokString, tokNumber, tokIdent:
+		v := Value{Scalar: p.tok.text}
+		if err := p.advance(); err != nil {
+			return Value{}, err
+		}
+		return v, nil
+
+	case tokLParen:
+		if err := p.advance(); err != nil {
+			return Value{}, err
+		}
+		var list []string
+		for {
+			if p.tok.kind != tokString && p.tok.kind != tokNumber {
+				return Value{}, fmt.Errorf("filter: expected a literal in list, got %q", p.tok.text)
+			}
+			list = append(list, p.tok.text)
+			if err := p.advance(); err != nil {
+				return Value{}, err
+			}
+			if p.tok.kind == tokComma {
+				if err := p.advance(); err != nil {
+					return Value{}, err
+				}
+				continue
+			}
+			break
+		}
+		if p.tok.kind != tokRParen {
+			return Value{}, fmt.Errorf("filter: expected ')', got %q", p.tok.text)
+		}
+		if err := p.advance(); err != nil {
+			return Value{}, err
+		}
+		return Value{List: list}, nil
+
+	default:
+		return Value{}, fmt.Errorf("filter: expected a value, got %q", p.tok.text)
+	}
+}
+
+// tokenKind identifies the lexical category of a token.
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokLParen
+	tokRParen
+	tokComma
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokIn
+	tokMatches
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// lexer scans a filter expression into tokens, one at a time.
+type lexer struct {
+	input []rune
+	pos   int
+}
+
+func newLexer(input string) *lexer {
+	return &lexer{input: []rune(input)}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipSpace()
+	if l.pos >= len(l.input) {
+		return token{kind: tokEOF}, nil
+	}
+
+	c := l.input[l.pos]
+	switch {
+	case c == '(':
+		l.pos++
+		return token{kind: tokLParen, text: "("}, nil
+	case c == ')':
+		l.pos++
+		return token{kind: tokRParen, text: ")"}, nil
+	case c == ',':
+		l.pos++
+		return token{kind: tokComma, text: ","}, nil
+	case c == '"':
+		return l.lexString()
+	case c == '=' || c == '!' || c == '<' || c == '>':
+		return l.lexOperator()
+	case unicode.IsDigit(c):
+		return l.lexNumber()
+	case unicode.IsLetter(c) || c == '_':
+		return l.lexIdentOrKeyword()
+	default:
+		return token{}, fmt.Errorf("filter: unexpected character %q", c)
+	}
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.input) && unicode.IsSpace(l.input[l.pos]) {
+		l.pos++
+	}
+}
+
+func (l *lexer) lexString() (token, error) {
+	l.pos++ // opening quote
+	start := l.pos
+	for l.pos < len(l.input) && l.input[l.pos] != '"' {
+		l.pos++
+	}
+	if l.pos >= len(l.input) {
+		return token{}, fmt.Errorf("filter: unterminated string literal")
+	}
+	text := string(l.input[start:l.pos])
+	l.pos++ // closing quote
+	return token{kind: tokString, text: text}, nil
+}
+
+func (l *lexer) lexNumber() (token, error) {
+	start := l.pos
+	for l.pos < len(l.input) && (unicode.IsDigit(l.input[l.pos]) || l.input[l.pos] == '.' || l.input[l.pos] == '-') {
+		l.pos++
+	}
+	return token{kind: tokNumber, text: string(l.input[start:l.pos])}, nil
+}
+
+func (l *lexer) lexIdentOrKeyword() (token, error) {
+	start := l.pos
+	for l.pos < len(l.input) && (unicode.IsLetter(l.input[l.pos]) || unicode.IsDigit(l.input[l.pos]) || l.input[l.pos] == '_' || l.input[l.pos] == '.') {
+		l.pos++
+	}
+	text := string(l.input[start:l.pos])
+
+	switch strings.ToLower(text) {
+	case "and":
+		return token{kind: tokAnd, text: text}, nil
+	case "or":
+		return token{kind: tokOr, text: text}, nil
+	case "not":
+		return token{kind: tokNot, text: text}, nil
+	case "in":
+		return token{kind: tokIn, text: "in"}, nil
+	case "matches":
+		return token{kind: tokMatches, text: "matches"}, nil
+	default:
+		return token{kind: tokIdent, text: text}, nil
+	}
+}
+
+func (l *lexer) lexOperator() (token, error) {
+	c := l.input[l.pos]
+	two := ""
+	if l.pos+1 < len(l.input) {
+		two = string(l.input[l.pos : l.pos+2])
+	}
+
+	switch {
+	case two == "==" || two == "!=" || two == "<=" || two == ">=":
+		l.pos += 2
+		return token{kind: tokOp, text: two}, nil
+	case c == '<' || c == '>':
+		l.pos++
+		return token{kind: tokOp, text: string(c)}, nil
+	default:
+		return token{}, fmt.Errorf("filter: unexpected character %q", c)
+	}
+}
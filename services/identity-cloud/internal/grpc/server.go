@@ -3,11 +3,15 @@ package grpc
 
 import (
 	"context"
-	"crypto/ed25519"
-	"encoding/base64"
+	"fmt"
+	"time"
 
 	"github.com/actoris/actoris/services/identity-cloud/internal/domain"
+	"github.com/actoris/actoris/services/identity-cloud/internal/resolver"
 	"github.com/actoris/actoris/services/identity-cloud/internal/service"
+	"github.com/actoris/actoris/services/identity-cloud/internal/vc"
+	"github.com/actoris/actoris/services/identity-cloud/internal/webhooks"
+	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -100,24 +104,59 @@ func (s *IdentityServer) ResolveLineage(ctx context.Context, req *ResolveLineage
 	lineageIDs = append(lineageIDs, identityToProto(currentIdentity))
 	trustInheritance = append(trustInheritance, 1.0) // Full trust for self
 
-	// Add ancestors
-	inheritanceFactor := domain.InheritedTrust
-	for _, ancestorDID := range lineage.Ancestors {
+	// Add the lineage chain, root first (lineage.Ancestors ends with req.Did
+	// itself). Each one's own trust inheritance factor is resolved
+	// independently via the lineage-weighted aggregation rather than
+	// compounding a single geometric decay down the chain, since each
+	// ancestor has its own sibling cohorts to trim outliers against.
+	var explanation domain.InheritanceExplanation
+	for i, ancestorDID := range lineage.Ancestors {
 		ancestor, err := s.svc.GetIdentity(ctx, ancestorDID)
 		if err != nil {
 			continue // Skip if ancestor not found
 		}
 		lineageIDs = append(lineageIDs, identityToProto(ancestor))
-		trustInheritance = append(trustInheritance, inheritanceFactor)
-		inheritanceFactor *= domain.InheritedTrust
+
+		tau, exp, err := s.svc.ResolveInheritedTau(ctx, ancestorDID)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to resolve inherited trust for %s: %v", ancestorDID, err)
+		}
+		trustInheritance = append(trustInheritance, tau)
+		if i == len(lineage.Ancestors)-2 {
+			// The second-to-last entry is req.Did's direct parent; its
+			// explanation is the one that actually produced req.Did's own
+			// inherited tau.
+			explanation = exp
+		}
 	}
 
 	return &ResolveLineageResponse{
-		Lineage:          lineageIDs,
-		TrustInheritance: trustInheritance,
+		Lineage:                lineageIDs,
+		TrustInheritance:       trustInheritance,
+		InheritanceExplanation: inheritanceExplanationToProto(explanation),
 	}, nil
 }
 
+// inheritanceExplanationToProto converts a domain.InheritanceExplanation into
+// its wire representation for auditability in ResolveLineageResponse.
+func inheritanceExplanationToProto(e domain.InheritanceExplanation) *InheritanceExplanation {
+	generations := make([]*GenerationWeight, 0, len(e.Generations))
+	for _, g := range e.Generations {
+		generations = append(generations, &GenerationWeight{
+			Depth:      int32(g.Depth),
+			Weight:     g.Weight,
+			TrimmedTau: g.TrimmedTau,
+			CohortSize: int32(g.CohortSize),
+			WasTrimmed: g.WasTrimmed,
+		})
+	}
+	return &InheritanceExplanation{
+		Generations: generations,
+		RawTau:      e.RawTau,
+		FinalTau:    e.FinalTau,
+	}
+}
+
 // GetTrustScore retrieves the trust score for an entity
 func (s *IdentityServer) GetTrustScore(ctx context.Context, req *GetTrustScoreRequest) (*GetTrustScoreResponse, error) {
 	if req.Did == "" {
@@ -183,6 +222,9 @@ func (s *IdentityServer) CreditWallet(ctx context.Context, req *CreditWalletRequ
 	if req.Amount == "" {
 		return nil, status.Error(codes.InvalidArgument, "amount is required")
 	}
+	if err := s.svc.CheckWalletHalt(ctx, req.Did); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
 
 	amount, err := decimal.NewFromString(req.Amount)
 	if err != nil {
@@ -193,7 +235,7 @@ func (s *IdentityServer) CreditWallet(ctx context.Context, req *CreditWalletRequ
 		return nil, status.Error(codes.InvalidArgument, "amount must be positive")
 	}
 
-	err = s.svc.CreditHC(ctx, req.Did, amount)
+	err = s.svc.CreditHC(ctx, req.Did, amount, idempotencyKeyOrRandom(req.IdempotencyKey))
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to credit wallet: %v", err)
 	}
@@ -208,6 +250,31 @@ func (s *IdentityServer) CreditWallet(ctx context.Context, req *CreditWalletRequ
 	}, nil
 }
 
+// GetWalletHistory returns a page of ledger entries for a wallet newer than
+// req.Cursor, plus the cursor to pass next time to resume after the last
+// entry returned. See Neo4jRepository.GetWalletHistory for the page_size
+// bound and clamping.
+func (s *IdentityServer) GetWalletHistory(ctx context.Context, req *GetWalletHistoryRequest) (*GetWalletHistoryResponse, error) {
+	if req.Did == "" {
+		return nil, status.Error(codes.InvalidArgument, "did is required")
+	}
+
+	transfers, nextCursor, err := s.svc.GetWalletHistory(ctx, req.Did, req.Cursor, int(req.PageSize))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get wallet history: %v", err)
+	}
+
+	protoTransfers := make([]*Transfer, 0, len(transfers))
+	for i := range transfers {
+		protoTransfers = append(protoTransfers, transferToProto(&transfers[i]))
+	}
+
+	return &GetWalletHistoryResponse{
+		Transfers:  protoTransfers,
+		NextCursor: nextCursor,
+	}, nil
+}
+
 // DebitWallet debits HC from a wallet
 func (s *IdentityServer) DebitWallet(ctx context.Context, req *DebitWalletRequest) (*DebitWalletResponse, error) {
 	if req.Did == "" {
@@ -216,6 +283,9 @@ func (s *IdentityServer) DebitWallet(ctx context.Context, req *DebitWalletReques
 	if req.Amount == "" {
 		return nil, status.Error(codes.InvalidArgument, "amount is required")
 	}
+	if err := s.svc.CheckWalletHalt(ctx, req.Did); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
 
 	amount, err := decimal.NewFromString(req.Amount)
 	if err != nil {
@@ -226,13 +296,15 @@ func (s *IdentityServer) DebitWallet(ctx context.Context, req *DebitWalletReques
 		return nil, status.Error(codes.InvalidArgument, "amount must be positive")
 	}
 
-	// Lock and release to simulate debit
-	err = s.svc.LockHCForEscrow(ctx, req.Did, amount)
+	// Lock and release to simulate debit. Each sub-operation gets its own
+	// derived key so a retry of the whole RPC dedups both legs.
+	idempotencyKey := idempotencyKeyOrRandom(req.IdempotencyKey)
+	err = s.svc.LockHCForEscrow(ctx, req.Did, amount, idempotencyKey+"-lock")
 	if err != nil {
 		return nil, status.Errorf(codes.FailedPrecondition, "failed to debit wallet: %v", err)
 	}
 
-	err = s.svc.ReleaseHCFromEscrow(ctx, req.Did, amount)
+	err = s.svc.ReleaseHCFromEscrow(ctx, req.Did, amount, idempotencyKey+"-release")
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to release from escrow: %v", err)
 	}
@@ -255,13 +327,16 @@ func (s *IdentityServer) LockWallet(ctx context.Context, req *LockWalletRequest)
 	if req.Amount == "" {
 		return nil, status.Error(codes.InvalidArgument, "amount is required")
 	}
+	if err := s.svc.CheckWalletHalt(ctx, req.Did); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
 
 	amount, err := decimal.NewFromString(req.Amount)
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid amount: %v", err)
 	}
 
-	err = s.svc.LockHCForEscrow(ctx, req.Did, amount)
+	err = s.svc.LockHCForEscrow(ctx, req.Did, amount, idempotencyKeyOrRandom(req.IdempotencyKey))
 	if err != nil {
 		return nil, status.Errorf(codes.FailedPrecondition, "failed to lock wallet: %v", err)
 	}
@@ -284,27 +359,32 @@ func (s *IdentityServer) ReleaseWallet(ctx context.Context, req *ReleaseWalletRe
 	if req.Amount == "" {
 		return nil, status.Error(codes.InvalidArgument, "amount is required")
 	}
+	if err := s.svc.CheckWalletHalt(ctx, req.Did); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+	}
 
 	amount, err := decimal.NewFromString(req.Amount)
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "invalid amount: %v", err)
 	}
 
+	idempotencyKey := idempotencyKeyOrRandom(req.IdempotencyKey)
+
 	// If target DID specified, transfer to them
 	if req.TargetDid != nil && *req.TargetDid != "" {
 		// Release from source
-		err = s.svc.ReleaseHCFromEscrow(ctx, req.Did, amount)
+		err = s.svc.ReleaseHCFromEscrow(ctx, req.Did, amount, idempotencyKey+"-release")
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, "failed to release escrow: %v", err)
 		}
 		// Credit to target
-		err = s.svc.CreditHC(ctx, *req.TargetDid, amount)
+		err = s.svc.CreditHC(ctx, *req.TargetDid, amount, idempotencyKey+"-credit")
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, "failed to credit target: %v", err)
 		}
 	} else {
 		// Refund back to available
-		err = s.svc.RefundHCFromEscrow(ctx, req.Did, amount)
+		err = s.svc.RefundHCFromEscrow(ctx, req.Did, amount, idempotencyKey)
 		if err != nil {
 			return nil, status.Errorf(codes.Internal, "failed to refund escrow: %v", err)
 		}
@@ -342,6 +422,407 @@ func (s *IdentityServer) VerifySignature(ctx context.Context, req *VerifySignatu
 	}, nil
 }
 
+// IssueCredential assembles and returns a Verifiable Credential for
+// subject_did, signed by issuer_did via a signature the caller supplies out
+// of band (IssueCredential does not sign with the issuer's derived key).
+func (s *IdentityServer) IssueCredential(ctx context.Context, req *IssueCredentialRequest) (*IssueCredentialResponse, error) {
+	if req.IssuerDid == "" {
+		return nil, status.Error(codes.InvalidArgument, "issuer_did is required")
+	}
+	if req.SubjectDid == "" {
+		return nil, status.Error(codes.InvalidArgument, "subject_did is required")
+	}
+	if len(req.Signature) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "signature is required")
+	}
+
+	format := protoToCredentialFormat(req.Format)
+	var expiration time.Time
+	if req.ExpiresAt > 0 {
+		expiration = time.UnixMilli(req.ExpiresAt)
+	}
+
+	cred, err := s.svc.IssueCredential(ctx, req.IssuerDid, req.SubjectDid, protoClaimsToDomain(req.Claims), expiration, req.Signature, format)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to issue credential: %v", err)
+	}
+
+	issuer, err := s.svc.GetIdentity(ctx, req.IssuerDid)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to resolve issuer for encoding: %v", err)
+	}
+	encoded, err := vc.Encode(cred, issuer.KeyType)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode credential: %v", err)
+	}
+
+	return &IssueCredentialResponse{
+		Credential: encoded,
+	}, nil
+}
+
+// VerifyCredential verifies a vc+jwt or JSON-LD credential's issuer
+// signature, expiration, and revocation status.
+func (s *IdentityServer) VerifyCredential(ctx context.Context, req *VerifyCredentialRequest) (*VerifyCredentialResponse, error) {
+	if req.Credential == "" {
+		return nil, status.Error(codes.InvalidArgument, "credential is required")
+	}
+
+	valid, issuerDID, subjectDID, claims, revoked, err := s.svc.VerifyCredential(ctx, req.Credential)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to verify credential: %v", err)
+	}
+
+	return &VerifyCredentialResponse{
+		Valid:   valid,
+		Issuer:  issuerDID,
+		Subject: subjectDID,
+		Claims:  domainClaimsToProto(claims),
+		Revoked: revoked,
+	}, nil
+}
+
+// RevokeCredential flips a credential's bit in its issuer's status list. The
+// request carries the full encoded credential, not a bare ID and index, so
+// the index actually flipped is read out of the signed document itself
+// rather than trusted from the caller.
+func (s *IdentityServer) RevokeCredential(ctx context.Context, req *RevokeCredentialRequest) (*RevokeCredentialResponse, error) {
+	if req.IssuerDid == "" {
+		return nil, status.Error(codes.InvalidArgument, "issuer_did is required")
+	}
+	if req.Credential == "" {
+		return nil, status.Error(codes.InvalidArgument, "credential is required")
+	}
+	if len(req.Signature) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "signature is required")
+	}
+
+	if err := s.svc.RevokeCredential(ctx, req.IssuerDid, req.Credential, req.Signature); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to revoke credential: %v", err)
+	}
+
+	return &RevokeCredentialResponse{}, nil
+}
+
+// VerifyPresentation verifies a holder's Verifiable Presentation, including
+// every credential embedded in it, binding the check to challenge and
+// domain so the presentation can't be replayed against a different verifier.
+func (s *IdentityServer) VerifyPresentation(ctx context.Context, req *VerifyPresentationRequest) (*VerifyPresentationResponse, error) {
+	if req.Presentation == "" {
+		return nil, status.Error(codes.InvalidArgument, "presentation is required")
+	}
+	if req.Challenge == "" {
+		return nil, status.Error(codes.InvalidArgument, "challenge is required")
+	}
+
+	valid, holderDID, creds, err := s.svc.VerifyPresentation(ctx, req.Presentation, req.Challenge, req.Domain)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to verify presentation: %v", err)
+	}
+
+	protoCreds := make([]*VerifiedCredential, 0, len(creds))
+	for i := range creds {
+		protoCreds = append(protoCreds, &VerifiedCredential{
+			Issuer:  creds[i].Issuer,
+			Subject: creds[i].Subject,
+			Claims:  domainClaimsToProto(creds[i].Claims),
+		})
+	}
+
+	return &VerifyPresentationResponse{
+		Valid:       valid,
+		Holder:      holderDID,
+		Credentials: protoCreds,
+	}, nil
+}
+
+// ProposeHalt proposes a governance-controlled freeze on wallet mutations
+func (s *IdentityServer) ProposeHalt(ctx context.Context, req *ProposeHaltRequest) (*ProposeHaltResponse, error) {
+	if req.ProposerDid == "" {
+		return nil, status.Error(codes.InvalidArgument, "proposer_did is required")
+	}
+	if req.Quorum <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "quorum must be positive")
+	}
+
+	halt, err := s.svc.ProposeHalt(ctx, req.ProposerDid, protoToHaltScope(req.Scope), req.Target, time.UnixMilli(req.EffectiveAt), req.Reason, int(req.Quorum))
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to propose halt: %v", err)
+	}
+
+	return &ProposeHaltResponse{
+		Halt: haltToProto(halt),
+	}, nil
+}
+
+// VoteHalt records a governance DID's signature for a halt proposal
+func (s *IdentityServer) VoteHalt(ctx context.Context, req *VoteHaltRequest) (*VoteHaltResponse, error) {
+	if req.HaltId == "" {
+		return nil, status.Error(codes.InvalidArgument, "halt_id is required")
+	}
+	if req.SignerDid == "" {
+		return nil, status.Error(codes.InvalidArgument, "signer_did is required")
+	}
+	if len(req.Signature) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "signature is required")
+	}
+
+	halt, err := s.svc.VoteHalt(ctx, req.HaltId, req.SignerDid, req.Signature)
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to record vote: %v", err)
+	}
+
+	return &VoteHaltResponse{
+		Halt: haltToProto(halt),
+	}, nil
+}
+
+// ListHalts lists proposed and active halts
+func (s *IdentityServer) ListHalts(ctx context.Context, req *ListHaltsRequest) (*ListHaltsResponse, error) {
+	halts, err := s.svc.ListHalts(ctx, req.ActiveOnly)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list halts: %v", err)
+	}
+
+	protoHalts := make([]*Halt, 0, len(halts))
+	for i := range halts {
+		protoHalts = append(protoHalts, haltToProto(&halts[i]))
+	}
+
+	return &ListHaltsResponse{
+		Halts: protoHalts,
+	}, nil
+}
+
+// CancelHalt cancels a halt proposal or active halt
+func (s *IdentityServer) CancelHalt(ctx context.Context, req *CancelHaltRequest) (*CancelHaltResponse, error) {
+	if req.HaltId == "" {
+		return nil, status.Error(codes.InvalidArgument, "halt_id is required")
+	}
+	if req.CallerDid == "" {
+		return nil, status.Error(codes.InvalidArgument, "caller_did is required")
+	}
+
+	if err := s.svc.CancelHalt(ctx, req.CallerDid, req.HaltId); err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to cancel halt: %v", err)
+	}
+
+	return &CancelHaltResponse{}, nil
+}
+
+// RegisterWebhook registers a new webhook subscription.
+func (s *IdentityServer) RegisterWebhook(ctx context.Context, req *RegisterWebhookRequest) (*RegisterWebhookResponse, error) {
+	if req.Url == "" {
+		return nil, status.Error(codes.InvalidArgument, "url is required")
+	}
+	if req.Secret == "" {
+		return nil, status.Error(codes.InvalidArgument, "secret is required")
+	}
+
+	var didFilter *string
+	if req.DidFilter != "" {
+		didFilter = &req.DidFilter
+	}
+
+	sub, err := s.svc.RegisterWebhook(ctx, req.Url, req.Secret, protoToWebhookEventTypes(req.EventTypes), didFilter)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to register webhook: %v", err)
+	}
+
+	return &RegisterWebhookResponse{
+		Webhook: webhookSubscriptionToProto(sub),
+	}, nil
+}
+
+// DeregisterWebhook removes a previously registered subscription.
+func (s *IdentityServer) DeregisterWebhook(ctx context.Context, req *DeregisterWebhookRequest) (*DeregisterWebhookResponse, error) {
+	if req.Id == "" {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+
+	id, err := uuid.Parse(req.Id)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid id: %v", err)
+	}
+
+	if err := s.svc.DeregisterWebhook(ctx, id); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to deregister webhook: %v", err)
+	}
+
+	return &DeregisterWebhookResponse{}, nil
+}
+
+// ListWebhookDeadLetters is an admin RPC listing webhook deliveries that
+// exhausted every retry attempt.
+func (s *IdentityServer) ListWebhookDeadLetters(ctx context.Context, req *ListWebhookDeadLettersRequest) (*ListWebhookDeadLettersResponse, error) {
+	deadLetters := s.svc.ListDeadLetterWebhooks()
+
+	protoDeadLetters := make([]*WebhookDeadLetter, 0, len(deadLetters))
+	for i := range deadLetters {
+		protoDeadLetters = append(protoDeadLetters, webhookDeadLetterToProto(&deadLetters[i]))
+	}
+
+	return &ListWebhookDeadLettersResponse{
+		DeadLetters: protoDeadLetters,
+	}, nil
+}
+
+// ResolveDID resolves an externally hosted did:web identity into its
+// normalized verification methods, so a caller can verify signatures from
+// identities this service never created.
+func (s *IdentityServer) ResolveDID(ctx context.Context, req *ResolveDIDRequest) (*ResolveDIDResponse, error) {
+	if req.Did == "" {
+		return nil, status.Error(codes.InvalidArgument, "did is required")
+	}
+
+	methods, err := s.svc.ResolveDID(ctx, req.Did)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to resolve DID: %v", err)
+	}
+
+	protoMethods := make([]*VerificationMethod, 0, len(methods))
+	for _, vm := range methods {
+		protoMethods = append(protoMethods, verificationMethodToProto(vm))
+	}
+
+	return &ResolveDIDResponse{
+		VerificationMethods: protoMethods,
+	}, nil
+}
+
+// ListSpawnedAgents returns a filtered, paginated page of agents spawned by
+// req.Did. Filter is a filter-package expression; see internal/filter for
+// the supported fields and operators.
+func (s *IdentityServer) ListSpawnedAgents(ctx context.Context, req *ListSpawnedAgentsRequest) (*ListSpawnedAgentsResponse, error) {
+	if req.Did == "" {
+		return nil, status.Error(codes.InvalidArgument, "did is required")
+	}
+
+	agents, nextPageToken, err := s.svc.ListSpawnedAgents(ctx, req.Did, req.Filter, req.PageToken, int(req.PageSize))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to list spawned agents: %v", err)
+	}
+
+	protoAgents := make([]*UnifiedID, 0, len(agents))
+	for i := range agents {
+		protoAgents = append(protoAgents, identityToProto(&agents[i]))
+	}
+
+	return &ListSpawnedAgentsResponse{
+		Agents:        protoAgents,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// WatchTrustScore streams trust score changes for a DID. If req.SinceVersion
+// is non-zero, buffered events newer than that version are replayed before
+// switching over to live updates.
+func (s *IdentityServer) WatchTrustScore(req *WatchTrustScoreRequest, stream IdentityService_WatchTrustScoreServer) error {
+	if req.Did == "" {
+		return status.Error(codes.InvalidArgument, "did is required")
+	}
+
+	ch, unsubscribe, backlog := s.svc.WatchTrustScore(req.Did, req.SinceVersion)
+	defer unsubscribe()
+
+	for _, e := range backlog {
+		if err := stream.Send(trustScoreEventToProto(e)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case e, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(trustScoreEventToProto(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// WatchWallet streams wallet changes for a DID. See WatchTrustScore for the
+// since_version replay semantics.
+func (s *IdentityServer) WatchWallet(req *WatchWalletRequest, stream IdentityService_WatchWalletServer) error {
+	if req.Did == "" {
+		return status.Error(codes.InvalidArgument, "did is required")
+	}
+
+	ch, unsubscribe, backlog := s.svc.WatchWallet(req.Did, req.SinceVersion)
+	defer unsubscribe()
+
+	for _, e := range backlog {
+		if err := stream.Send(walletEventToProto(e)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case e, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(walletEventToProto(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// WatchLineage streams trust score and wallet changes for root_did and every
+// identity it has spawned, directly or transitively, as a single merged
+// stream. See WatchTrustScore for the since_version replay semantics.
+func (s *IdentityServer) WatchLineage(req *WatchLineageRequest, stream IdentityService_WatchLineageServer) error {
+	if req.RootDid == "" {
+		return status.Error(codes.InvalidArgument, "root_did is required")
+	}
+
+	ctx := stream.Context()
+	ch, unsubscribe, backlog, err := s.svc.WatchLineage(ctx, req.RootDid, req.SinceVersion)
+	if err != nil {
+		return status.Errorf(codes.NotFound, "failed to resolve lineage: %v", err)
+	}
+	defer unsubscribe()
+
+	for _, e := range backlog {
+		if err := stream.Send(lineageEventToProto(e)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case e, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if err := stream.Send(lineageEventToProto(e)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// idempotencyKeyOrRandom returns key if the caller supplied one, otherwise
+// mints a random one so the ledger-level idempotency check in the wallet
+// methods always has something to key off of.
+func idempotencyKeyOrRandom(key string) string {
+	if key != "" {
+		return key
+	}
+	return uuid.NewString()
+}
+
 // Helper functions for proto conversion
 
 func protoToEntityType(et EntityType) domain.EntityType {
@@ -370,6 +851,33 @@ func entityTypeToProto(et domain.EntityType) EntityType {
 	}
 }
 
+func protoToCredentialFormat(f CredentialFormat) domain.CredentialFormat {
+	switch f {
+	case CredentialFormat_CREDENTIAL_FORMAT_JWT:
+		return domain.CredentialFormatJWT
+	case CredentialFormat_CREDENTIAL_FORMAT_JSONLD:
+		return domain.CredentialFormatJSONLD
+	default:
+		return domain.CredentialFormatUnspecified
+	}
+}
+
+func protoClaimsToDomain(claims map[string]string) map[string]any {
+	out := make(map[string]any, len(claims))
+	for k, v := range claims {
+		out[k] = v
+	}
+	return out
+}
+
+func domainClaimsToProto(claims map[string]any) map[string]string {
+	out := make(map[string]string, len(claims))
+	for k, v := range claims {
+		out[k] = fmt.Sprintf("%v", v)
+	}
+	return out
+}
+
 func identityToProto(id *domain.UnifiedID) *UnifiedID {
 	if id == nil {
 		return nil
@@ -402,6 +910,21 @@ func trustScoreToProto(ts *domain.TrustScore) *TrustScore {
 	}
 }
 
+func transferToProto(t *domain.Transfer) *Transfer {
+	if t == nil {
+		return nil
+	}
+	return &Transfer{
+		Id:         t.ID.String(),
+		FromDid:    t.FromDID,
+		ToDid:      t.ToDID,
+		Amount:     t.Amount.String(),
+		Kind:       t.Kind,
+		BlockIndex: t.BlockIndex,
+		CreatedAt:  t.CreatedAt.UnixMilli(),
+	}
+}
+
 func walletToProto(w *domain.HCWallet) *HcWallet {
 	if w == nil {
 		return nil
@@ -416,12 +939,173 @@ func walletToProto(w *domain.HCWallet) *HcWallet {
 	}
 }
 
-// generateDIDKey generates a did:key from an Ed25519 public key
-func generateDIDKey(publicKey ed25519.PublicKey) string {
-	// Multicodec prefix for Ed25519 public key (0xed01)
-	multicodec := []byte{0xed, 0x01}
-	encoded := append(multicodec, publicKey...)
+func protoToHaltScope(s HaltScope) domain.HaltScope {
+	switch s {
+	case HaltScope_HALT_SCOPE_GLOBAL:
+		return domain.HaltScopeGlobal
+	case HaltScope_HALT_SCOPE_DID:
+		return domain.HaltScopeDID
+	case HaltScope_HALT_SCOPE_ENTITY_TYPE:
+		return domain.HaltScopeEntityType
+	default:
+		return domain.HaltScopeUnspecified
+	}
+}
+
+func haltScopeToProto(s domain.HaltScope) HaltScope {
+	switch s {
+	case domain.HaltScopeGlobal:
+		return HaltScope_HALT_SCOPE_GLOBAL
+	case domain.HaltScopeDID:
+		return HaltScope_HALT_SCOPE_DID
+	case domain.HaltScopeEntityType:
+		return HaltScope_HALT_SCOPE_ENTITY_TYPE
+	default:
+		return HaltScope_HALT_SCOPE_UNSPECIFIED
+	}
+}
+
+func haltStatusToProto(s domain.HaltStatus) HaltStatus {
+	switch s {
+	case domain.HaltStatusPending:
+		return HaltStatus_HALT_STATUS_PENDING
+	case domain.HaltStatusActive:
+		return HaltStatus_HALT_STATUS_ACTIVE
+	case domain.HaltStatusCancelled:
+		return HaltStatus_HALT_STATUS_CANCELLED
+	default:
+		return HaltStatus_HALT_STATUS_UNSPECIFIED
+	}
+}
+
+// protoToWebhookEventTypes converts the wire string list to domain event
+// types, no validation beyond the type conversion: an unrecognized type
+// simply never matches any emitted event, matching WebhookSubscription.Matches.
+func protoToWebhookEventTypes(types []string) []domain.WebhookEventType {
+	out := make([]domain.WebhookEventType, len(types))
+	for i, t := range types {
+		out[i] = domain.WebhookEventType(t)
+	}
+	return out
+}
+
+func webhookSubscriptionToProto(sub *domain.WebhookSubscription) *WebhookSubscription {
+	if sub == nil {
+		return nil
+	}
+	eventTypes := make([]string, len(sub.EventTypes))
+	for i, t := range sub.EventTypes {
+		eventTypes[i] = string(t)
+	}
+	out := &WebhookSubscription{
+		Id:         sub.ID.String(),
+		Url:        sub.URL,
+		EventTypes: eventTypes,
+		CreatedAt:  sub.CreatedAt.UnixMilli(),
+	}
+	if sub.DIDFilter != nil {
+		out.DidFilter = *sub.DIDFilter
+	}
+	return out
+}
+
+func webhookDeadLetterToProto(d *webhooks.DeadLetter) *WebhookDeadLetter {
+	if d == nil {
+		return nil
+	}
+	return &WebhookDeadLetter{
+		Webhook:   webhookSubscriptionToProto(&d.Subscription),
+		EventType: string(d.Event.Type),
+		Did:       d.Event.DID,
+		Attempts:  int32(d.Attempts),
+		LastError: d.LastError,
+		FailedAt:  d.FailedAt.UnixMilli(),
+	}
+}
+
+// verificationMethodToProto converts a resolved DID document entry to its
+// wire form. PublicKey is the raw key bytes; KeyType is its String() form
+// (e.g. "Ed25519"), matching how other enum-like domain values are already
+// carried as strings on the wire elsewhere in this file.
+func verificationMethodToProto(vm resolver.VerificationMethod) *VerificationMethod {
+	return &VerificationMethod{
+		Id:         vm.ID,
+		Controller: vm.Controller,
+		KeyType:    vm.KeyType.String(),
+		PublicKey:  vm.PublicKey,
+	}
+}
+
+func haltToProto(h *domain.HaltEntry) *Halt {
+	if h == nil {
+		return nil
+	}
+	signerDIDs := make([]string, 0, len(h.Signatures))
+	for _, sig := range h.Signatures {
+		signerDIDs = append(signerDIDs, sig.SignerDID)
+	}
+	return &Halt{
+		Id:          h.ID,
+		Scope:       haltScopeToProto(h.Scope),
+		Target:      h.Target,
+		EffectiveAt: h.EffectiveAt.UnixMilli(),
+		Reason:      h.Reason,
+		ProposedBy:  h.ProposedBy,
+		Quorum:      int32(h.Quorum),
+		SignerDids:  signerDIDs,
+		Status:      haltStatusToProto(h.Status),
+		CreatedAt:   h.CreatedAt.UnixMilli(),
+	}
+}
+
+func claimableTrustDeltaToProto(d *domain.ClaimableTrustDelta) *ClaimableTrustDelta {
+	if d == nil {
+		return nil
+	}
+	return &ClaimableTrustDelta{
+		VerificationScore: d.VerificationScore,
+		SlaScore:          d.SLAScore,
+		Score:             d.Score,
+		VerifiedOutcomes:  d.VerifiedOutcomes,
+		PendingOutcomes:   d.PendingOutcomes,
+	}
+}
+
+func trustScoreEventToProto(e service.Event) *TrustScoreEvent {
+	delta, _ := e.Data.(*domain.ClaimableTrustDelta)
+	return &TrustScoreEvent{
+		Did:     e.DID,
+		Version: e.Version,
+		Cursor:  e.Cursor,
+		Delta:   claimableTrustDeltaToProto(delta),
+	}
+}
+
+func walletEventToProto(e service.Event) *WalletEvent {
+	wallet, _ := e.Data.(*domain.HCWallet)
+	return &WalletEvent{
+		Did:     e.DID,
+		Version: e.Version,
+		Cursor:  e.Cursor,
+		Wallet:  walletToProto(wallet),
+	}
+}
 
-	// Base58btc encode (simplified - using base64url for now)
-	return "did:key:z" + base64.RawURLEncoding.EncodeToString(encoded)
+// lineageEventToProto converts a fanned-in WatchLineage event, which may
+// carry either trust score or wallet data depending on e.Topic.
+func lineageEventToProto(e service.Event) *LineageEvent {
+	out := &LineageEvent{
+		Did:     e.DID,
+		Version: e.Version,
+		Cursor:  e.Cursor,
+	}
+	switch e.Topic {
+	case service.TopicWallet:
+		wallet, _ := e.Data.(*domain.HCWallet)
+		out.Wallet = walletToProto(wallet)
+	case service.TopicTrustScore:
+		delta, _ := e.Data.(*domain.ClaimableTrustDelta)
+		out.TrustDelta = claimableTrustDeltaToProto(delta)
+	}
+	return out
 }
@@ -0,0 +1,119 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ResponseFactories maps a gRPC method name (grpc.UnaryServerInfo.FullMethod)
+// to a constructor for a fresh zero-value response, used to unmarshal a
+// cached response back into its concrete type on replay.
+type ResponseFactories map[string]func() any
+
+// UnaryServerInterceptor returns a grpc.UnaryServerInterceptor that
+// deduplicates requests carrying a non-empty IdempotencyKey field: a repeat
+// call with the same key and an identical request replays the cached
+// response; a repeat call with the same key but a different request is
+// rejected with codes.AlreadyExists; concurrent retries for the same key
+// coalesce onto a single execution via an in-process keyed mutex. Requests
+// without an IdempotencyKey field, or with an empty one, pass through
+// unchanged.
+func UnaryServerInterceptor(store Store, ttl time.Duration, responses ResponseFactories) grpc.UnaryServerInterceptor {
+	mutex := newKeyedMutex()
+
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		key, ok := stringField(req, "IdempotencyKey")
+		if !ok || key == "" {
+			return handler(ctx, req)
+		}
+		did, _ := stringField(req, "Did")
+
+		unlock := mutex.lock(key)
+		defer unlock()
+
+		requestHash := hashRequest(req)
+		existing, reserved, err := store.Reserve(ctx, key, did, info.FullMethod, requestHash, ttl)
+		if err != nil {
+			if errors.Is(err, ErrConflict) {
+				return nil, status.Errorf(codes.AlreadyExists, "idempotency key %q was already used for a different request", key)
+			}
+			return nil, status.Errorf(codes.Internal, "idempotency check failed: %v", err)
+		}
+
+		if !reserved {
+			switch existing.Status {
+			case StatusOK, StatusError:
+				return decodeResponse(responses, info.FullMethod, existing.Response)
+			default:
+				// Another call is still executing under this key on a
+				// different replica; the keyed mutex only coalesces retries
+				// on this instance, so surface this as a retryable conflict.
+				return nil, status.Errorf(codes.AlreadyExists, "idempotency key %q is already in flight", key)
+			}
+		}
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			_ = store.Delete(ctx, key)
+			return nil, err
+		}
+
+		if encoded, encErr := json.Marshal(resp); encErr == nil {
+			_ = store.Complete(ctx, key, StatusOK, encoded)
+		}
+		return resp, nil
+	}
+}
+
+func decodeResponse(responses ResponseFactories, fullMethod string, cached []byte) (any, error) {
+	newResponse, ok := responses[fullMethod]
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "no response factory registered for %s", fullMethod)
+	}
+	resp := newResponse()
+	if err := json.Unmarshal(cached, resp); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to decode cached response: %v", err)
+	}
+	return resp, nil
+}
+
+// hashRequest hashes req's JSON encoding so Reserve can detect a key reused
+// with a materially different request.
+func hashRequest(req any) string {
+	encoded, err := json.Marshal(req)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// stringField reads a string-typed exported field named name off req via
+// reflection, since the request types here are plain structs rather than
+// proto.Message implementations with getters.
+func stringField(req any, name string) (string, bool) {
+	v := reflect.ValueOf(req)
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return "", false
+	}
+	f := v.FieldByName(name)
+	if !f.IsValid() || f.Kind() != reflect.String {
+		return "", false
+	}
+	return f.String(), true
+}
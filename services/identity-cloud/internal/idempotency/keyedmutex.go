@@ -0,0 +1,44 @@
+package idempotency
+
+import "sync"
+
+// keyedMutex hands out a per-key lock so concurrent retries carrying the
+// same idempotency key serialize onto a single execution instead of racing
+// each other to Reserve. Entries are reference-counted and removed once the
+// last holder releases, so the map doesn't grow unbounded.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*refMutex
+}
+
+type refMutex struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: make(map[string]*refMutex)}
+}
+
+// lock acquires the lock for key and returns a function that releases it.
+func (m *keyedMutex) lock(key string) func() {
+	m.mu.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &refMutex{}
+		m.locks[key] = l
+	}
+	l.refs++
+	m.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		m.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(m.locks, key)
+		}
+		m.mu.Unlock()
+	}
+}
@@ -0,0 +1,73 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-process Store backed by a map, suitable for a single
+// replica or for tests. It does not coordinate across instances; use
+// PostgresStore when the service is horizontally scaled.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]*memoryRecord
+}
+
+type memoryRecord struct {
+	record    Record
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an empty in-memory idempotency store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*memoryRecord)}
+}
+
+func (s *MemoryStore) Reserve(ctx context.Context, key, did, method, requestHash string, ttl time.Duration) (*Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := s.records[key]; ok && now.Before(existing.expiresAt) {
+		if existing.record.RequestHash != requestHash {
+			return nil, false, ErrConflict
+		}
+		rec := existing.record
+		return &rec, false, nil
+	}
+
+	s.records[key] = &memoryRecord{
+		record: Record{
+			Key:         key,
+			DID:         did,
+			Method:      method,
+			RequestHash: requestHash,
+			Status:      StatusPending,
+			CreatedAt:   now,
+		},
+		expiresAt: now.Add(ttl),
+	}
+	return nil, true, nil
+}
+
+func (s *MemoryStore) Complete(ctx context.Context, key, status string, response []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.records[key]
+	if !ok {
+		return nil
+	}
+	existing.record.Status = status
+	existing.record.Response = response
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
@@ -0,0 +1,106 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// PostgresStore is a Store backed by Postgres, for deployments with more
+// than one IdentityCloud replica where an in-process MemoryStore can't
+// coordinate across instances. It depends only on database/sql, so the
+// caller supplies an already-opened *sql.DB with whichever driver they
+// registered (e.g. pgx or lib/pq).
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore wraps an open *sql.DB as an idempotency Store.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+const createIdempotencyTableSQL = `
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+	key          TEXT PRIMARY KEY,
+	did          TEXT NOT NULL,
+	method       TEXT NOT NULL,
+	request_hash TEXT NOT NULL,
+	response     BYTEA,
+	status       TEXT NOT NULL,
+	created_at   TIMESTAMPTZ NOT NULL,
+	expires_at   TIMESTAMPTZ NOT NULL
+)`
+
+// CreateTable creates the idempotency_keys table if it does not already
+// exist. Call this once during service startup.
+func (s *PostgresStore) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, createIdempotencyTableSQL)
+	return err
+}
+
+// reserveSQL claims key for a fresh execution, reusing the row in place if
+// it already exists but has expired. WHERE idempotency_keys.expires_at <
+// now() means the UPDATE (and its RETURNING) only fires when the existing
+// row is stale; a live conflicting row returns zero rows instead.
+const reserveSQL = `
+INSERT INTO idempotency_keys (key, did, method, request_hash, status, created_at, expires_at)
+VALUES ($1, $2, $3, $4, 'pending', now(), now() + make_interval(secs => $5))
+ON CONFLICT (key) DO UPDATE
+	SET did = EXCLUDED.did,
+		method = EXCLUDED.method,
+		request_hash = EXCLUDED.request_hash,
+		status = EXCLUDED.status,
+		response = NULL,
+		created_at = EXCLUDED.created_at,
+		expires_at = EXCLUDED.expires_at
+	WHERE idempotency_keys.expires_at < now()
+RETURNING true
+`
+
+func (s *PostgresStore) Reserve(ctx context.Context, key, did, method, requestHash string, ttl time.Duration) (*Record, bool, error) {
+	var inserted bool
+	err := s.db.QueryRowContext(ctx, reserveSQL, key, did, method, requestHash, ttl.Seconds()).Scan(&inserted)
+	if err == nil {
+		return nil, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	existing, err := s.get(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing.RequestHash != requestHash {
+		return nil, false, ErrConflict
+	}
+	return existing, false, nil
+}
+
+func (s *PostgresStore) get(ctx context.Context, key string) (*Record, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT did, method, request_hash, response, status, created_at
+		FROM idempotency_keys
+		WHERE key = $1 AND expires_at >= now()
+	`, key)
+
+	rec := &Record{Key: key}
+	if err := row.Scan(&rec.DID, &rec.Method, &rec.RequestHash, &rec.Response, &rec.Status, &rec.CreatedAt); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (s *PostgresStore) Complete(ctx context.Context, key, status string, response []byte) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE idempotency_keys SET status = $2, response = $3 WHERE key = $1
+	`, key, status, response)
+	return err
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key = $1`, key)
+	return err
+}
@@ -0,0 +1,59 @@
+// Package idempotency provides request de-duplication for gRPC methods that
+// accept a client-supplied idempotency key: a retried call with the same key
+// and the same request replays the cached response instead of re-executing a
+// mutation, and a retried call with the same key but a different request is
+// rejected as a conflict.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrConflict is returned by Store.Reserve when key has already been used
+// for a request with a different hash.
+var ErrConflict = errors.New("idempotency key reused with a different request")
+
+// Status values a Record can be in.
+const (
+	StatusPending = "pending" // reserved, execution in progress
+	StatusOK      = "ok"
+	StatusError   = "error"
+)
+
+// Record is one idempotency ledger entry.
+type Record struct {
+	Key         string
+	DID         string
+	Method      string
+	RequestHash string
+	Response    []byte // the cached response, serialized by the caller
+	Status      string
+	CreatedAt   time.Time
+}
+
+// Store atomically records idempotency keys and their outcomes, with a
+// configurable TTL after which a key may be reused. Implementations must
+// make Reserve race-safe against concurrent callers claiming the same key
+// (e.g. via a unique constraint or compare-and-swap), since the in-process
+// keyedMutex only coalesces retries that land on the same server instance.
+type Store interface {
+	// Reserve atomically claims key for a new execution. If no record exists
+	// for key, it creates one with StatusPending and returns
+	// (nil, true, nil): the caller owns the execution and must call Complete
+	// or Delete when done. If a record already exists, Reserve returns it
+	// with reserved=false: the caller should replay Response if Status is
+	// terminal (StatusOK/StatusError), or treat it as a concurrent in-flight
+	// call if Status is StatusPending. If the existing record's RequestHash
+	// does not match requestHash, Reserve returns ErrConflict.
+	Reserve(ctx context.Context, key, did, method, requestHash string, ttl time.Duration) (existing *Record, reserved bool, err error)
+
+	// Complete fills in the terminal outcome for a key this caller reserved.
+	Complete(ctx context.Context, key, status string, response []byte) error
+
+	// Delete removes a reservation, used to release a key when the
+	// reserving call fails before Complete so retries are not stuck forever
+	// behind a dead pending record.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,84 @@
+// Package keys derives per-identity Ed25519 keypairs from a single master
+// seed (the per-app-wallet-key pattern): nothing but the seed and each
+// identity's derivation path ever needs to be kept around, and the path
+// itself is safe to persist since it reveals nothing about the derived key
+// without the seed.
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/actoris/actoris/services/identity-cloud/internal/domain"
+)
+
+// SeedSize is the required length of the master seed (IDENTITY_MASTER_SEED).
+const SeedSize = 32
+
+// DerivationPath identifies the inputs that deterministically produce an
+// identity's Ed25519 keypair from the master seed: the parent it was
+// spawned under (empty for a root identity), its entity type, and a
+// monotonic counter scoped to that parent. Only the path is persisted in
+// Neo4j; the derived private key never is.
+type DerivationPath struct {
+	ParentDID  string
+	EntityType domain.EntityType
+	Index      uint64
+}
+
+// Derive deterministically derives an Ed25519 keypair for path from
+// masterSeed via HKDF-SHA512, so the same (masterSeed, path) pair always
+// reproduces the same keys.
+func Derive(masterSeed []byte, path DerivationPath) (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	if len(masterSeed) != SeedSize {
+		return nil, nil, fmt.Errorf("master seed must be %d bytes, got %d", SeedSize, len(masterSeed))
+	}
+
+	info := make([]byte, 0, len(path.ParentDID)+9)
+	info = append(info, path.ParentDID...)
+	info = append(info, byte(path.EntityType))
+	info = binary.BigEndian.AppendUint64(info, path.Index)
+
+	seed, err := hkdfSHA512(masterSeed, info, ed25519.SeedSize)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to derive key seed: %w", err)
+	}
+
+	priv := ed25519.NewKeyFromSeed(seed)
+	return priv.Public().(ed25519.PublicKey), priv, nil
+}
+
+// hkdfSHA512 implements RFC 5869 HKDF (extract-then-expand) over SHA-512,
+// producing length pseudorandom bytes from ikm (the master seed) bound to
+// info (the derivation path). salt is omitted (per RFC 5869 that's
+// equivalent to a salt of all-zero hash-length bytes), since the master
+// seed is already a high-entropy secret and doesn't need one.
+func hkdfSHA512(ikm, info []byte, length int) ([]byte, error) {
+	const hashSize = sha512.Size
+	if length > 255*hashSize {
+		return nil, fmt.Errorf("requested length %d exceeds HKDF-SHA512 maximum", length)
+	}
+
+	// Extract
+	zeroSalt := make([]byte, hashSize)
+	extractMAC := hmac.New(sha512.New, zeroSalt)
+	extractMAC.Write(ikm)
+	prk := extractMAC.Sum(nil)
+
+	// Expand
+	out := make([]byte, 0, length+hashSize)
+	var prev []byte
+	for counter := byte(1); len(out) < length; counter++ {
+		expandMAC := hmac.New(sha512.New, prk)
+		expandMAC.Write(prev)
+		expandMAC.Write(info)
+		expandMAC.Write([]byte{counter})
+		prev = expandMAC.Sum(nil)
+		out = append(out, prev...)
+	}
+	return out[:length], nil
+}
+
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// AllocateStatusListIndex reserves the next free slot in issuerDID's
+// status-list revocation registry (creating the list on its first
+// credential) and returns the index assigned to the new credential.
+func (r *Neo4jRepository) AllocateStatusListIndex(ctx context.Context, issuerDID string) (uint32, error) {
+	result, err := r.withSession(ctx, neo4j.AccessModeWrite, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txAllocateStatusListIndex(ctx, tx, issuerDID)
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.(uint32), nil
+}
+
+func txAllocateStatusListIndex(ctx context.Context, tx neo4j.ManagedTransaction, issuerDID string) (uint32, error) {
+	query := `
+		MERGE (s:StatusList {issuer_did: $issuer_did})
+		ON CREATE SET s.bits = []
+		SET s.bits = s.bits + [false]
+		RETURN size(s.bits) - 1 as index
+	`
+	result, err := tx.Run(ctx, query, map[string]any{"issuer_did": issuerDID})
+	if err != nil {
+		return 0, err
+	}
+	record, err := result.Single(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate status list index: %w", err)
+	}
+	index, ok := record.Values[0].(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected index type from status list allocation")
+	}
+	return uint32(index), nil
+}
+
+// RevokeCredential flips the bit at index in issuerDID's status list,
+// marking the credential issued at that slot as revoked.
+func (r *Neo4jRepository) RevokeCredential(ctx context.Context, issuerDID string, index uint32) error {
+	_, err := r.withSession(ctx, neo4j.AccessModeWrite, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return nil, txRevokeCredential(ctx, tx, issuerDID, index)
+		})
+	})
+	return err
+}
+
+func txRevokeCredential(ctx context.Context, tx neo4j.ManagedTransaction, issuerDID string, index uint32) error {
+	query := `
+		MATCH (s:StatusList {issuer_did: $issuer_did})
+		WHERE $index < size(s.bits)
+		SET s.bits = [i IN range(0, size(s.bits) - 1) | CASE WHEN i = $index THEN true ELSE s.bits[i] END]
+	`
+	result, err := tx.Run(ctx, query, map[string]any{"issuer_did": issuerDID, "index": int64(index)})
+	if err != nil {
+		return err
+	}
+	summary, err := result.Consume(ctx)
+	if err != nil {
+		return err
+	}
+	if summary.Counters().PropertiesSet() == 0 {
+		return fmt.Errorf("status list entry not found for issuer %s index %d", issuerDID, index)
+	}
+	return nil
+}
+
+// IsCredentialRevoked checks issuerDID's status list for the bit at index,
+// without needing to contact the issuer. An issuer with no status list yet,
+// or an index beyond its current list, is treated as not revoked.
+func (r *Neo4jRepository) IsCredentialRevoked(ctx context.Context, issuerDID string, index uint32) (bool, error) {
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txIsCredentialRevoked(ctx, tx, issuerDID, index)
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+	return result.(bool), nil
+}
+
+func txIsCredentialRevoked(ctx context.Context, tx neo4j.ManagedTransaction, issuerDID string, index uint32) (bool, error) {
+	query := `
+		MATCH (s:StatusList {issuer_did: $issuer_did})
+		WHERE $index < size(s.bits)
+		RETURN s.bits[$index] as revoked
+	`
+	result, err := tx.Run(ctx, query, map[string]any{"issuer_did": issuerDID, "index": int64(index)})
+	if err != nil {
+		return false, err
+	}
+	if result.Next(ctx) {
+		revoked, _ := result.Record().Values[0].(bool)
+		return revoked, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// derivationScopeRoot is the counter scope key used for identities with no
+// parent, since DerivationCounter nodes are keyed by parent DID and a root
+// identity has none.
+const derivationScopeRoot = "__root__"
+
+// AllocateDerivationIndex reserves the next key-derivation index scoped to
+// parentDID (or the root scope, if parentDID is empty), so concurrently
+// spawned siblings never collide on the same HKDF derivation path.
+func (r *Neo4jRepository) AllocateDerivationIndex(ctx context.Context, parentDID string) (uint64, error) {
+	scope := parentDID
+	if scope == "" {
+		scope = derivationScopeRoot
+	}
+
+	result, err := r.withSession(ctx, neo4j.AccessModeWrite, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txAllocateDerivationIndex(ctx, tx, scope)
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.(uint64), nil
+}
+
+func txAllocateDerivationIndex(ctx context.Context, tx neo4j.ManagedTransaction, scope string) (uint64, error) {
+	query := `
+		MERGE (c:DerivationCounter {scope: $scope})
+		ON CREATE SET c.next = 0
+		SET c.next = c.next + 1
+		RETURN c.next - 1 as index
+	`
+	result, err := tx.Run(ctx, query, map[string]any{"scope": scope})
+	if err != nil {
+		return 0, err
+	}
+	record, err := result.Single(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to allocate derivation index: %w", err)
+	}
+	index, ok := record.Values[0].(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected index type from derivation counter allocation")
+	}
+	return uint64(index), nil
+}
@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/actoris/actoris/services/identity-cloud/internal/domain"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// ProposeHalt creates a new pending halt proposal as a one-shot Tx.
+func (r *Neo4jRepository) ProposeHalt(ctx context.Context, halt *domain.HaltEntry) error {
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := tx.ProposeHalt(ctx, halt); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ProposeHalt creates a new pending halt proposal within this transaction.
+func (t *Tx) ProposeHalt(ctx context.Context, halt *domain.HaltEntry) error {
+	return txProposeHalt(ctx, t.tx, halt)
+}
+
+func txProposeHalt(ctx context.Context, tx neo4j.ManagedTransaction, halt *domain.HaltEntry) error {
+	query := `
+		CREATE (h:Halt {
+			id: $id,
+			scope: $scope,
+			target: $target,
+			effective_at: datetime($effective_at),
+			reason: $reason,
+			proposed_by: $proposed_by,
+			quorum: $quorum,
+			status: $status,
+			created_at: datetime()
+		})
+	`
+	result, err := tx.Run(ctx, query, map[string]any{
+		"id":           halt.ID,
+		"scope":        halt.Scope.String(),
+		"target":       halt.Target,
+		"effective_at": halt.EffectiveAt.UTC().Format(time.RFC3339Nano),
+		"reason":       halt.Reason,
+		"proposed_by":  halt.ProposedBy,
+		"quorum":       halt.Quorum,
+		"status":       domain.HaltStatusPending.String(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = result.Consume(ctx)
+	return err
+}
+
+// VoteHalt records a governance DID's signature for a halt proposal and
+// promotes it to active once quorum is reached. It returns the halt's state
+// after the vote is counted.
+func (r *Neo4jRepository) VoteHalt(ctx context.Context, haltID, signerDID string, signature []byte) (*domain.HaltEntry, error) {
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	halt, err := tx.VoteHalt(ctx, haltID, signerDID, signature)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return halt, nil
+}
+
+// VoteHalt records a governance DID's signature within this transaction.
+func (t *Tx) VoteHalt(ctx context.Context, haltID, signerDID string, signature []byte) (*domain.HaltEntry, error) {
+	return txVoteHalt(ctx, t.tx, haltID, signerDID, signature)
+}
+
+func txVoteHalt(ctx context.Context, tx neo4j.ManagedTransaction, haltID, signerDID string, signature []byte) (*domain.HaltEntry, error) {
+	query := `
+		MATCH (h:Halt {id: $halt_id})
+		WHERE h.status <> $cancelled
+		MERGE (h)-[:SIGNED_BY]->(s:HaltSignature {signer_did: $signer_did})
+		ON CREATE SET s.signature = $signature, s.signed_at = datetime()
+		WITH h
+		MATCH (h)-[:SIGNED_BY]->(sig:HaltSignature)
+		WITH h, count(sig) as sig_count
+		FOREACH (_ IN CASE WHEN sig_count >= h.quorum AND h.status = $pending THEN [1] ELSE [] END |
+			SET h.status = $active
+		)
+		RETURN h.id, h.scope, h.target, h.effective_at, h.reason, h.proposed_by,
+			   h.quorum, h.status, h.created_at, h.cancelled_at
+	`
+	result, err := tx.Run(ctx, query, map[string]any{
+		"halt_id":    haltID,
+		"signer_did": signerDID,
+		"signature":  signature,
+		"cancelled":  domain.HaltStatusCancelled.String(),
+		"pending":    domain.HaltStatusPending.String(),
+		"active":     domain.HaltStatusActive.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := result.Single(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("halt not found or already cancelled: %s", haltID)
+	}
+	return haltFromRecord(record.Values), nil
+}
+
+// ListHalts returns every proposed halt, or only those currently active
+// (quorum reached, not cancelled, effective) when activeOnly is set.
+func (r *Neo4jRepository) ListHalts(ctx context.Context, activeOnly bool) ([]domain.HaltEntry, error) {
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txListHalts(ctx, tx, activeOnly)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]domain.HaltEntry), nil
+}
+
+// ListHalts returns halt proposals within this transaction. See the one-shot
+// ListHalts method for the activeOnly semantics.
+func (t *Tx) ListHalts(ctx context.Context, activeOnly bool) ([]domain.HaltEntry, error) {
+	return txListHalts(ctx, t.tx, activeOnly)
+}
+
+func txListHalts(ctx context.Context, tx neo4j.ManagedTransaction, activeOnly bool) ([]domain.HaltEntry, error) {
+	query := `
+		MATCH (h:Halt)
+		WHERE NOT $active_only OR (h.status = $active AND h.effective_at <= datetime())
+		RETURN h.id, h.scope, h.target, h.effective_at, h.reason, h.proposed_by,
+			   h.quorum, h.status, h.created_at, h.cancelled_at
+		ORDER BY h.created_at DESC
+	`
+	result, err := tx.Run(ctx, query, map[string]any{
+		"active_only": activeOnly,
+		"active":      domain.HaltStatusActive.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	halts := []domain.HaltEntry{}
+	for result.Next(ctx) {
+		halt := haltFromRecord(result.Record().Values)
+		sigs, err := txGetHaltSignatures(ctx, tx, halt.ID)
+		if err != nil {
+			return nil, err
+		}
+		halt.Signatures = sigs
+		halts = append(halts, *halt)
+	}
+	return halts, nil
+}
+
+func txGetHaltSignatures(ctx context.Context, tx neo4j.ManagedTransaction, haltID string) ([]domain.HaltSignature, error) {
+	query := `
+		MATCH (:Halt {id: $halt_id})-[:SIGNED_BY]->(s:HaltSignature)
+		RETURN s.signer_did as signer_did, s.signature as signature, s.signed_at as signed_at
+		ORDER BY s.signed_at ASC
+	`
+	result, err := tx.Run(ctx, query, map[string]any{"halt_id": haltID})
+	if err != nil {
+		return nil, err
+	}
+
+	var sigs []domain.HaltSignature
+	for result.Next(ctx) {
+		record := result.Record()
+		sig := domain.HaltSignature{}
+		if signer, ok := record.Values[0].(string); ok {
+			sig.SignerDID = signer
+		}
+		if signature, ok := record.Values[1].([]byte); ok {
+			sig.Signature = signature
+		}
+		if signedAt, ok := record.Values[2].(neo4j.LocalDateTime); ok {
+			sig.SignedAt = signedAt.Time()
+		}
+		sigs = append(sigs, sig)
+	}
+	return sigs, nil
+}
+
+// CancelHalt cancels a halt proposal or active halt, lifting any wallet
+// freeze it was enforcing.
+func (r *Neo4jRepository) CancelHalt(ctx context.Context, haltID string) error {
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := tx.CancelHalt(ctx, haltID); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// CancelHalt cancels a halt proposal within this transaction.
+func (t *Tx) CancelHalt(ctx context.Context, haltID string) error {
+	return txCancelHalt(ctx, t.tx, haltID)
+}
+
+func txCancelHalt(ctx context.Context, tx neo4j.ManagedTransaction, haltID string) error {
+	query := `
+		MATCH (h:Halt {id: $halt_id})
+		SET h.status = $cancelled, h.cancelled_at = datetime()
+	`
+	result, err := tx.Run(ctx, query, map[string]any{
+		"halt_id":   haltID,
+		"cancelled": domain.HaltStatusCancelled.String(),
+	})
+	if err != nil {
+		return err
+	}
+	summary, err := result.Consume(ctx)
+	if err != nil {
+		return err
+	}
+	if summary.Counters().PropertiesSet() == 0 {
+		return fmt.Errorf("halt not found: %s", haltID)
+	}
+	return nil
+}
+
+func haltFromRecord(values []any) *domain.HaltEntry {
+	halt := &domain.HaltEntry{}
+	if id, ok := values[0].(string); ok {
+		halt.ID = id
+	}
+	if scope, ok := values[1].(string); ok {
+		halt.Scope = parseHaltScope(scope)
+	}
+	if target, ok := values[2].(string); ok {
+		halt.Target = target
+	}
+	if effectiveAt, ok := values[3].(neo4j.LocalDateTime); ok {
+		halt.EffectiveAt = effectiveAt.Time()
+	}
+	if reason, ok := values[4].(string); ok {
+		halt.Reason = reason
+	}
+	if proposedBy, ok := values[5].(string); ok {
+		halt.ProposedBy = proposedBy
+	}
+	if quorum, ok := values[6].(int64); ok {
+		halt.Quorum = int(quorum)
+	}
+	if status, ok := values[7].(string); ok {
+		halt.Status = parseHaltStatus(status)
+	}
+	if createdAt, ok := values[8].(neo4j.LocalDateTime); ok {
+		halt.CreatedAt = createdAt.Time()
+	}
+	if cancelledAt, ok := values[9].(neo4j.LocalDateTime); ok {
+		t := cancelledAt.Time()
+		halt.CancelledAt = &t
+	}
+	return halt
+}
+
+func parseHaltScope(s string) domain.HaltScope {
+	switch s {
+	case "global":
+		return domain.HaltScopeGlobal
+	case "did":
+		return domain.HaltScopeDID
+	case "entity_type":
+		return domain.HaltScopeEntityType
+	default:
+		return domain.HaltScopeUnspecified
+	}
+}
+
+func parseHaltStatus(s string) domain.HaltStatus {
+	switch s {
+	case "pending":
+		return domain.HaltStatusPending
+	case "active":
+		return domain.HaltStatusActive
+	case "cancelled":
+		return domain.HaltStatusCancelled
+	default:
+		return domain.HaltStatusUnspecified
+	}
+}
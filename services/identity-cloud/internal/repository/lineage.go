@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+
+	"github.com/actoris/actoris/services/identity-cloud/internal/domain"
+)
+
+// GetSiblingCohortTaus returns the normalized tau (score/MaxScore) of every
+// child of parentDID that has a trust score, including the child the caller
+// is treating as "the ancestor" for that generation. An identity with no
+// trust score yet (still on the default) is skipped rather than reported as
+// zero, since it has no opinion to contribute to the cohort.
+//
+// This is a batch read, not a per-sibling GetTrustScore call, so it can't
+// fold GetTrustScore's claim-then-read behavior in on its own: it claims
+// every sibling's pending trust delta first, best-effort, so the batch query
+// that follows sees cohort taus as fresh as a direct GetTrustScore call
+// would, rather than stale-until-next-unrelated-read.
+func (r *Neo4jRepository) GetSiblingCohortTaus(ctx context.Context, parentDID string) ([]float64, error) {
+	siblings, err := r.GetSpawnedAgents(ctx, parentDID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sibling := range siblings {
+		trust, err := r.getTrustScore(ctx, sibling.DID)
+		if err != nil || trust == nil {
+			continue
+		}
+		_ = r.ClaimTrustDelta(ctx, sibling.DID, trust.Version)
+	}
+
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txGetSiblingCohortTaus(ctx, tx, parentDID)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]float64), nil
+}
+
+func txGetSiblingCohortTaus(ctx context.Context, tx neo4j.ManagedTransaction, parentDID string) ([]float64, error) {
+	query := `
+		MATCH (parent:Identity {did: $parent_did})-[:SPAWNED]->(sibling:Identity)-[:HAS_TRUST]->(t:TrustScore)
+		RETURN t.score as score
+	`
+	result, err := tx.Run(ctx, query, map[string]any{"parent_did": parentDID})
+	if err != nil {
+		return nil, err
+	}
+
+	var taus []float64
+	for result.Next(ctx) {
+		record := result.Record()
+		score, ok := record.Values[0].(int64)
+		if !ok {
+			continue
+		}
+		taus = append(taus, float64(score)/float64(domain.MaxScore))
+	}
+	return taus, nil
+}
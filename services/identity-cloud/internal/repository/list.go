@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/actoris/actoris/services/identity-cloud/internal/domain"
+	"github.com/actoris/actoris/services/identity-cloud/internal/filter"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// maxListPageSize bounds how many rows a single ListSpawnedAgents call can
+// request, so an unbounded pageSize can't force one query to buffer an
+// entire organization's agents.
+const maxListPageSize = 500
+
+// ListSpawnedAgents retrieves agents spawned by did, filtered by filterExpr
+// (a filter-package expression; empty means no filter) and paginated via
+// pageToken/pageSize. It returns the page and the token for the next page,
+// which is empty once there are no more results.
+func (r *Neo4jRepository) ListSpawnedAgents(ctx context.Context, did, filterExpr, pageToken string, pageSize int) ([]domain.UnifiedID, string, error) {
+	ctx = withDID(ctx, did)
+
+	if pageSize <= 0 || pageSize > maxListPageSize {
+		pageSize = maxListPageSize
+	}
+
+	var compiled *filter.Compiled
+	if filterExpr != "" {
+		expr, err := filter.Parse(filterExpr)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid filter: %w", err)
+		}
+		compiled, err = filter.Compile(expr)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+
+	cursor, err := filter.DecodeCursor(pageToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txListSpawnedAgents(ctx, tx, did, compiled, cursor, pageSize)
+		})
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	page := result.(listSpawnedAgentsResult)
+	return page.agents, page.nextPageToken, nil
+}
+
+type listSpawnedAgentsResult struct {
+	agents        []domain.UnifiedID
+	nextPageToken string
+}
+
+func txListSpawnedAgents(ctx context.Context, tx neo4j.ManagedTransaction, did string, compiled *filter.Compiled, cursor filter.Cursor, pageSize int) (listSpawnedAgentsResult, error) {
+	var conditions []string
+	params := map[string]any{"did": did, "limit": int64(pageSize + 1)}
+
+	if compiled != nil {
+		conditions = append(conditions, compiled.Cypher)
+		for k, v := range compiled.Params {
+			params[k] = v
+		}
+	}
+	if cursor.DID != "" {
+		conditions = append(conditions, "(child.created_at > datetime($cursor_sort_key) OR (child.created_at = datetime($cursor_sort_key) AND child.did > $cursor_did))")
+		params["cursor_sort_key"] = cursor.SortKey
+		params["cursor_did"] = cursor.DID
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf(`
+		MATCH (parent:Identity {did: $did})-[:SPAWNED]->(child:Identity)
+		MATCH (child)-[:HAS_TRUST]->(trust:TrustScore)
+		MATCH (child)-[:OWNS_WALLET]->(wallet:HCWallet)
+		%s
+		RETURN child.did as did,
+			   child.entity_type as entity_type,
+			   child.created_at as created_at,
+			   child.public_key as public_key,
+			   child.key_type as key_type
+		ORDER BY child.created_at ASC, child.did ASC
+		LIMIT $limit
+	`, where)
+
+	result, err := tx.Run(ctx, query, params)
+	if err != nil {
+		return listSpawnedAgentsResult{}, err
+	}
+
+	var agents []domain.UnifiedID
+	for result.Next(ctx) {
+		record := result.Record()
+		agent := domain.UnifiedID{
+			DID:       record.Values[0].(string),
+			ParentDID: &did,
+		}
+
+		switch record.Values[1].(string) {
+		case "human":
+			agent.EntityType = domain.EntityTypeHuman
+		case "agent":
+			agent.EntityType = domain.EntityTypeAgent
+		case "organization":
+			agent.EntityType = domain.EntityTypeOrganization
+		}
+
+		switch createdAt := record.Values[2].(type) {
+		case time.Time:
+			agent.CreatedAt = createdAt
+		case neo4j.LocalDateTime:
+			agent.CreatedAt = createdAt.Time()
+		}
+		if pk, ok := record.Values[3].([]byte); ok {
+			agent.PublicKey = pk
+		}
+		if keyType, ok := record.Values[4].(string); ok {
+			agent.KeyType = parseKeyType(keyType)
+		}
+
+		agents = append(agents, agent)
+	}
+	if err := result.Err(); err != nil {
+		return listSpawnedAgentsResult{}, err
+	}
+
+	// One extra row was fetched (LIMIT pageSize+1) purely to detect whether
+	// a next page exists; it's trimmed off before returning.
+	nextPageToken := ""
+	if len(agents) > pageSize {
+		agents = agents[:pageSize]
+		last := agents[len(agents)-1]
+		nextPageToken, err = filter.EncodeCursor(last.CreatedAt.Format(time.RFC3339Nano), last.DID)
+		if err != nil {
+			return listSpawnedAgentsResult{}, err
+		}
+	}
+
+	return listSpawnedAgentsResult{agents: agents, nextPageToken: nextPageToken}, nil
+}
@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// endpointRequestsTotal counts Neo4j requests per endpoint and outcome, so
+// operators can see which cluster members are absorbing traffic and which
+// are failing, independent of the per-endpoint EndpointStatus snapshot
+// exposed by HealthCheck.
+var endpointRequestsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "identity_cloud_neo4j_endpoint_requests_total",
+		Help: "Total Neo4j requests per endpoint, labeled by outcome (success/failure).",
+	},
+	[]string{"endpoint", "result"},
+)
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"math/big"
+
+	"github.com/shopspring/decimal"
+)
+
+// hcScale is the number of decimal places HC wallet balances are stored with
+// on the graph: 10^18 minor units per HC. Converting a decimal.Decimal to
+// minor units truncates any digits beyond the 18th decimal place. Balances
+// are persisted as plain base-10 integer strings in minor units rather than
+// floats, since Cypher's toFloat()/toString() round trip silently truncates
+// beyond ~15 significant digits.
+const hcScale = 18
+
+// decimalToMinorUnits converts a decimal.Decimal HC amount to its integer
+// minor-unit representation (HC * 10^hcScale).
+func decimalToMinorUnits(d decimal.Decimal) *big.Int {
+	return d.Shift(hcScale).BigInt()
+}
+
+// minorUnitsToDecimal converts an integer minor-unit amount back to HC.
+func minorUnitsToDecimal(minor *big.Int) decimal.Decimal {
+	return decimal.NewFromBigInt(minor, -hcScale)
+}
+
+// parseMinorUnits parses a base-10 integer string as minor units. It returns
+// zero if s is empty or malformed, mirroring the tolerant parsing the rest
+// of this package does for decimal.NewFromString.
+func parseMinorUnits(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return big.NewInt(0)
+	}
+	return v
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestDecimalToMinorUnitsAndBack(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"whole number", "42"},
+		{"simple fraction", "0.5"},
+		{"more than 15 significant decimal digits", "1.123456789012345678"},
+		{"zero", "0"},
+		{"negative amount", "-3.14"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := decimal.NewFromString(tt.input)
+			if err != nil {
+				t.Fatalf("decimal.NewFromString(%q): %v", tt.input, err)
+			}
+
+			minor := decimalToMinorUnits(d)
+			roundTripped := minorUnitsToDecimal(minor)
+
+			if !roundTripped.Equal(d) {
+				t.Errorf("round trip of %q = %v, want %v", tt.input, roundTripped, d)
+			}
+		})
+	}
+}
+
+func TestDecimalToMinorUnitsPreservesFullPrecision(t *testing.T) {
+	// Float64 conversion would have silently rounded this; minor units must not.
+	d := decimal.RequireFromString("0.123456789012345678")
+	want, _ := new(big.Int).SetString("123456789012345678", 10)
+
+	got := decimalToMinorUnits(d)
+	if got.Cmp(want) != 0 {
+		t.Errorf("decimalToMinorUnits(%v) = %v, want %v", d, got, want)
+	}
+}
+
+func TestParseMinorUnits(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  *big.Int
+	}{
+		{"valid positive", "123456789012345678", big.NewInt(123456789012345678)},
+		{"valid negative", "-42", big.NewInt(-42)},
+		{"empty string defaults to zero", "", big.NewInt(0)},
+		{"malformed string defaults to zero", "not-a-number", big.NewInt(0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseMinorUnits(tt.input)
+			if got.Cmp(tt.want) != 0 {
+				t.Errorf("parseMinorUnits(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMinorUnitsRoundTripsWithString(t *testing.T) {
+	original := decimalToMinorUnits(decimal.RequireFromString("99.000000000000000001"))
+	parsed := parseMinorUnits(original.String())
+
+	if parsed.Cmp(original) != 0 {
+		t.Errorf("parseMinorUnits(original.String()) = %v, want %v", parsed, original)
+	}
+}
@@ -4,499 +4,1641 @@ package repository
 import (
 	"context"
 	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/actoris/actoris/services/identity-cloud/internal/domain"
+	"github.com/google/uuid"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/shopspring/decimal"
 )
 
-// Neo4jRepository provides Neo4j database operations for identity management
+// endpointCooldown is how long a failed endpoint is skipped before being retried.
+const endpointCooldown = 30 * time.Second
+
+// endpoint tracks the lazily-opened driver and health state for one Neo4j URI.
+type endpoint struct {
+	uri    string
+	mu     sync.Mutex
+	driver neo4j.DriverWithContext // nil until first use
+
+	lastErr       error
+	lastErrorAt   time.Time
+	lastSuccessAt time.Time
+	successCount  uint64
+	failureCount  uint64
+}
+
+// EndpointStatus is a point-in-time snapshot of one endpoint's health, returned
+// by HealthCheck and used to drive endpoint selection metrics.
+type EndpointStatus struct {
+	URI           string
+	Healthy       bool
+	LastError     error
+	LastErrorAt   time.Time
+	LastSuccessAt time.Time
+	SuccessCount  uint64
+	FailureCount  uint64
+}
+
+// Neo4jRepository provides Neo4j database operations for identity management.
+// It maintains one driver per configured endpoint and fails over between them
+// on connection-level errors, so a single downed cluster member doesn't take
+// the service down. Reads round-robin across healthy endpoints; writes pin to
+// the last endpoint known to be the cluster leader and only move off it on a
+// connection failure or a Neo.ClientError.Cluster.NotALeader response.
 type Neo4jRepository struct {
-	driver neo4j.DriverWithContext
+	username string
+	password string
+
+	endpoints []*endpoint
+	cursor    uint64 // atomic round-robin cursor across endpoints
+	leaderIdx int64  // atomic index into endpoints of the last known write leader, -1 if unknown
+}
+
+// NewNeo4jRepository creates a new Neo4j repository backed by one or more
+// endpoints. Drivers are opened lazily per endpoint and cached for the life
+// of the repository; at least one endpoint must verify connectivity at
+// construction time, but the rest may come up later.
+func NewNeo4jRepository(ctx context.Context, uris []string, username, password string) (*Neo4jRepository, error) {
+	if len(uris) == 0 {
+		return nil, fmt.Errorf("at least one neo4j uri is required")
+	}
+
+	r := &Neo4jRepository{username: username, password: password, leaderIdx: -1}
+	for _, uri := range uris {
+		r.endpoints = append(r.endpoints, &endpoint{uri: uri})
+	}
+
+	var lastErr error
+	for _, ep := range r.endpoints {
+		if err := r.verify(ctx, ep); err != nil {
+			lastErr = err
+			continue
+		}
+		lastErr = nil
+		break
+	}
+	if lastErr != nil {
+		return nil, fmt.Errorf("failed to verify connectivity to any neo4j endpoint: %w", lastErr)
+	}
+
+	return r, nil
+}
+
+// driverFor returns the cached driver for an endpoint, opening it on first use.
+func (r *Neo4jRepository) driverFor(ep *endpoint) (neo4j.DriverWithContext, error) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	if ep.driver != nil {
+		return ep.driver, nil
+	}
+
+	driver, err := neo4j.NewDriverWithContext(ep.uri, neo4j.BasicAuth(r.username, r.password, ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create neo4j driver for %s: %w", ep.uri, err)
+	}
+	ep.driver = driver
+	return driver, nil
 }
 
-// NewNeo4jRepository creates a new Neo4j repository
-func NewNeo4jRepository(ctx context.Context, uri, username, password string) (*Neo4jRepository, error) {
-	driver, err := neo4j.NewDriverWithContext(uri, neo4j.BasicAuth(username, password, ""))
+// verify opens (if needed) and connectivity-checks a single endpoint.
+func (r *Neo4jRepository) verify(ctx context.Context, ep *endpoint) error {
+	driver, err := r.driverFor(ep)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create neo4j driver: %w", err)
+		r.recordFailure(ep, err)
+		return err
 	}
 
-	// Verify connectivity with timeout
 	verifyCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	if err := driver.VerifyConnectivity(verifyCtx); err != nil {
-		return nil, fmt.Errorf("failed to verify neo4j connectivity: %w", err)
+		r.recordFailure(ep, err)
+		return fmt.Errorf("failed to verify neo4j connectivity to %s: %w", ep.uri, err)
 	}
 
-	return &Neo4jRepository{driver: driver}, nil
+	r.recordSuccess(ep)
+	return nil
 }
 
-// ExecuteWrite executes a write query with parameters
-func (r *Neo4jRepository) ExecuteWrite(ctx context.Context, query string, params map[string]any) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
-	defer session.Close(ctx)
+func (r *Neo4jRepository) recordSuccess(ep *endpoint) {
+	atomic.AddUint64(&ep.successCount, 1)
+	ep.mu.Lock()
+	ep.lastSuccessAt = time.Now()
+	ep.mu.Unlock()
+	endpointRequestsTotal.WithLabelValues(ep.uri, "success").Inc()
+}
+
+func (r *Neo4jRepository) recordFailure(ep *endpoint, err error) {
+	atomic.AddUint64(&ep.failureCount, 1)
+	endpointRequestsTotal.WithLabelValues(ep.uri, "failure").Inc()
+	ep.mu.Lock()
+	ep.lastErr = err
+	ep.lastErrorAt = time.Now()
+	ep.mu.Unlock()
+}
+
+// inCooldown reports whether an endpoint failed recently enough that it
+// should be skipped in favor of other endpoints.
+func (r *Neo4jRepository) inCooldown(ep *endpoint) bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.lastErrorAt.IsZero() {
+		return false
+	}
+	if ep.lastSuccessAt.After(ep.lastErrorAt) {
+		return false
+	}
+	return time.Since(ep.lastErrorAt) < endpointCooldown
+}
+
+// orderedEndpoints returns the endpoint list starting from a rotating cursor,
+// so repeated calls spread load across healthy endpoints instead of always
+// hammering the first one in the list.
+func (r *Neo4jRepository) orderedEndpoints() []*endpoint {
+	n := len(r.endpoints)
+	start := int(atomic.AddUint64(&r.cursor, 1)) % n
+	ordered := make([]*endpoint, 0, n)
+	ordered = append(ordered, r.endpoints[start:]...)
+	ordered = append(ordered, r.endpoints[:start]...)
+	return ordered
+}
+
+// writeEndpointOrder returns the endpoint list for a write attempt: the
+// pinned leader first (if one is known), followed by the rest in their
+// configured order. Unlike orderedEndpoints, this intentionally does not
+// rotate, since writes should keep hitting the known leader instead of
+// spreading load.
+func (r *Neo4jRepository) writeEndpointOrder() []*endpoint {
+	n := len(r.endpoints)
+	leader := int(atomic.LoadInt64(&r.leaderIdx))
+	if leader < 0 || leader >= n {
+		return append([]*endpoint(nil), r.endpoints...)
+	}
+
+	ordered := make([]*endpoint, 0, n)
+	ordered = append(ordered, r.endpoints[leader])
+	for i, ep := range r.endpoints {
+		if i != leader {
+			ordered = append(ordered, ep)
+		}
+	}
+	return ordered
+}
 
-	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		result, err := tx.Run(ctx, query, params)
+// setLeader records ep as the endpoint that most recently accepted a write,
+// so subsequent writes are pinned to it instead of round-robining.
+func (r *Neo4jRepository) setLeader(ep *endpoint) {
+	for i, e := range r.endpoints {
+		if e == ep {
+			atomic.StoreInt64(&r.leaderIdx, int64(i))
+			return
+		}
+	}
+}
+
+// isNotALeaderError reports whether err is a Neo4j cluster routing error
+// indicating the endpoint we wrote to is no longer (or never was) the
+// leader. Like a connection error, this should trigger failover to the next
+// endpoint on a write, but it is an application-level Cypher error rather
+// than a transport failure, so isConnectionError alone wouldn't catch it.
+func isNotALeaderError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return strings.Contains(err.Error(), "Neo.ClientError.Cluster.NotALeader")
+}
+
+// isConnectionError reports whether err looks like a connection-level
+// failure (dropped socket, unreachable host, driver unavailable) as opposed
+// to a Cypher logic error such as an optimistic-lock version mismatch.
+// Logic errors must not trigger failover, since retrying them against a
+// different endpoint would just repeat the same application-level failure.
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, substr := range []string{
+		"ServiceUnavailable",
+		"connection refused",
+		"connection reset",
+		"no route to host",
+		"i/o timeout",
+		"broken pipe",
+		"unable to connect",
+		"context deadline exceeded",
+		"EOF",
+	} {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// withSession runs fn against a session on a healthy endpoint, rotating to
+// the next endpoint on connection-level failures. Reads round-robin across
+// all healthy endpoints; writes are pinned to the last known leader and only
+// move off it on a connection failure or a NotALeader response, so a write
+// that lands on a follower fails over to the real leader instead of
+// retrying the same follower. Other Cypher logic errors (e.g. a failed
+// optimistic-lock guard) are returned immediately without failover, since
+// another endpoint would return the same result.
+func (r *Neo4jRepository) withSession(ctx context.Context, mode neo4j.AccessMode, fn func(neo4j.SessionWithContext) (any, error)) (any, error) {
+	ctx, span := startSessionSpan(ctx, mode)
+	defer span.End()
+
+	result, err := r.withSessionTraced(ctx, mode, fn)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return result, err
+}
+
+func (r *Neo4jRepository) withSessionTraced(ctx context.Context, mode neo4j.AccessMode, fn func(neo4j.SessionWithContext) (any, error)) (any, error) {
+	var lastErr error
+	attempted := 0
+
+	order := r.orderedEndpoints()
+	if mode == neo4j.AccessModeWrite {
+		order = r.writeEndpointOrder()
+	}
+
+	for _, ep := range order {
+		if r.inCooldown(ep) {
+			continue
+		}
+
+		driver, err := r.driverFor(ep)
 		if err != nil {
+			lastErr = err
+			r.recordFailure(ep, err)
+			continue
+		}
+		attempted++
+
+		session := driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: mode})
+		result, err := fn(session)
+		closeErr := session.Close(ctx)
+
+		if err == nil {
+			if closeErr != nil && isConnectionError(closeErr) {
+				r.recordFailure(ep, closeErr)
+				lastErr = closeErr
+				continue
+			}
+			r.recordSuccess(ep)
+			if mode == neo4j.AccessModeWrite {
+				r.setLeader(ep)
+			}
+			return result, nil
+		}
+
+		if mode == neo4j.AccessModeWrite && isNotALeaderError(err) {
+			r.recordFailure(ep, err)
+			lastErr = err
+			continue
+		}
+
+		if !isConnectionError(err) {
+			// Logic error - surface directly, don't fail over.
 			return nil, err
 		}
-		return result.Consume(ctx)
+
+		r.recordFailure(ep, err)
+		lastErr = err
+	}
+
+	if attempted == 0 {
+		return nil, fmt.Errorf("no healthy neo4j endpoints available")
+	}
+	return nil, fmt.Errorf("all neo4j endpoints failed: %w", lastErr)
+}
+
+// HealthCheck probes every configured endpoint and returns its current
+// status, so operators can see which nodes are serving traffic.
+func (r *Neo4jRepository) HealthCheck(ctx context.Context) []EndpointStatus {
+	statuses := make([]EndpointStatus, 0, len(r.endpoints))
+	for _, ep := range r.endpoints {
+		err := r.verify(ctx, ep)
+
+		ep.mu.Lock()
+		status := EndpointStatus{
+			URI:           ep.uri,
+			Healthy:       err == nil,
+			LastError:     ep.lastErr,
+			LastErrorAt:   ep.lastErrorAt,
+			LastSuccessAt: ep.lastSuccessAt,
+			SuccessCount:  atomic.LoadUint64(&ep.successCount),
+			FailureCount:  atomic.LoadUint64(&ep.failureCount),
+		}
+		ep.mu.Unlock()
+
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// ExecuteWrite executes a write query with parameters
+func (r *Neo4jRepository) ExecuteWrite(ctx context.Context, query string, params map[string]any) error {
+	_, err := r.withSession(ctx, neo4j.AccessModeWrite, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			result, err := tx.Run(ctx, query, params)
+			if err != nil {
+				return nil, err
+			}
+			return result.Consume(ctx)
+		})
 	})
 	return err
 }
 
-// Close closes the Neo4j driver
+// Close closes every endpoint's Neo4j driver.
 func (r *Neo4jRepository) Close(ctx context.Context) error {
-	return r.driver.Close(ctx)
+	var firstErr error
+	for _, ep := range r.endpoints {
+		ep.mu.Lock()
+		driver := ep.driver
+		ep.mu.Unlock()
+		if driver == nil {
+			continue
+		}
+		if err := driver.Close(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
 
-// CreateIdentity creates a new UnifiedID in the graph
-func (r *Neo4jRepository) CreateIdentity(ctx context.Context, id *domain.UnifiedID) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
-	defer session.Close(ctx)
-
-	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		query := `
-			CREATE (i:Identity {
-				did: $did,
-				entity_type: $entity_type,
-				created_at: datetime($created_at),
-				public_key: $public_key
-			})
-			WITH i
-			// If parent exists, create SPAWNED relationship
-			OPTIONAL MATCH (parent:Identity {did: $parent_did})
-			FOREACH (p IN CASE WHEN parent IS NOT NULL THEN [parent] ELSE [] END |
-				CREATE (p)-[:SPAWNED {created_at: datetime($created_at)}]->(i)
-			)
-			// Create initial trust score
-			CREATE (i)-[:HAS_TRUST]->(t:TrustScore {
-				score: 500,
-				verification_score: 200,
-				dispute_penalty: 0,
-				sla_score: 100,
-				network_score: 200,
-				updated_at: datetime($created_at),
-				verified_outcomes: 0,
-				dispute_rate: 0.0,
-				version: 1
-			})
-			// Create HC wallet
-			CREATE (i)-[:OWNS_WALLET]->(w:HCWallet {
-				available: '0',
-				locked: '0',
-				expires_at: datetime() + duration('P30D'),
-				version: 1,
-				updated_at: datetime($created_at)
-			})
-			RETURN i.did
-		`
-		params := map[string]any{
-			"did":         id.DID,
-			"entity_type": id.EntityType.String(),
-			"created_at":  id.CreatedAt.Format(time.RFC3339),
-			"public_key":  id.PublicKey,
-			"parent_did":  nil,
+// Tx is an explicit, caller-controlled transaction against one Neo4j
+// endpoint. It exposes the same mutation/read method set as Neo4jRepository,
+// so a caller that needs to batch several operations (e.g. lock HC on one
+// wallet and credit another) can do so in a single round trip instead of
+// paying per-call session setup N times, then decide once whether to Commit
+// or Rollback.
+//
+// Tx always opens a write-mode session, since a batch may freely mix reads
+// and writes; callers that only need read-replica routing for a single
+// query should keep using the one-shot repository methods instead.
+type Tx struct {
+	repo    *Neo4jRepository
+	ep      *endpoint
+	session neo4j.SessionWithContext
+	tx      neo4j.ExplicitTransaction
+}
+
+// Begin opens a new Tx against the pinned write leader (or, when none is
+// known, the first healthy endpoint in configured order), moving to the next
+// endpoint on connection-level failures the same way withSession does.
+func (r *Neo4jRepository) Begin(ctx context.Context) (*Tx, error) {
+	var lastErr error
+	attempted := 0
+
+	for _, ep := range r.writeEndpointOrder() {
+		if r.inCooldown(ep) {
+			continue
 		}
-		if id.ParentDID != nil {
-			params["parent_did"] = *id.ParentDID
+
+		driver, err := r.driverFor(ep)
+		if err != nil {
+			lastErr = err
+			r.recordFailure(ep, err)
+			continue
 		}
+		attempted++
 
-		result, err := tx.Run(ctx, query, params)
+		session := driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+		tx, err := session.BeginTransaction(ctx)
 		if err != nil {
-			return nil, err
+			session.Close(ctx)
+			if !isConnectionError(err) {
+				return nil, err
+			}
+			r.recordFailure(ep, err)
+			lastErr = err
+			continue
 		}
-		return result.Consume(ctx)
+
+		return &Tx{repo: r, ep: ep, session: session, tx: tx}, nil
+	}
+
+	if attempted == 0 {
+		return nil, fmt.Errorf("no healthy neo4j endpoints available")
+	}
+	return nil, fmt.Errorf("all neo4j endpoints failed: %w", lastErr)
+}
+
+// Commit commits the transaction and closes its session.
+func (t *Tx) Commit(ctx context.Context) error {
+	err := t.tx.Commit(ctx)
+	closeErr := t.session.Close(ctx)
+	if err != nil {
+		t.repo.recordFailure(t.ep, err)
+		return err
+	}
+	if closeErr != nil {
+		t.repo.recordFailure(t.ep, closeErr)
+		return closeErr
+	}
+	t.repo.recordSuccess(t.ep)
+	t.repo.setLeader(t.ep)
+	return nil
+}
+
+// Rollback rolls back the transaction and closes its session. Calling
+// Rollback after a successful Commit is a no-op error callers can ignore.
+func (t *Tx) Rollback(ctx context.Context) error {
+	err := t.tx.Rollback(ctx)
+	closeErr := t.session.Close(ctx)
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// CreateIdentity creates a new UnifiedID in the graph within this transaction.
+func (t *Tx) CreateIdentity(ctx context.Context, id *domain.UnifiedID) error {
+	return txCreateIdentity(ctx, t.tx, id)
+}
+
+// GetIdentity retrieves a UnifiedID by DID within this transaction.
+func (t *Tx) GetIdentity(ctx context.Context, did string) (*domain.UnifiedID, error) {
+	return txGetIdentity(ctx, t.tx, did)
+}
+
+// GetTrustScore retrieves the trust score for an identity within this transaction.
+func (t *Tx) GetTrustScore(ctx context.Context, did string) (*domain.TrustScore, error) {
+	return txGetTrustScore(ctx, t.tx, did)
+}
+
+// UpdateTrustScore updates the trust score for an identity within this transaction.
+func (t *Tx) UpdateTrustScore(ctx context.Context, did string, update *domain.TrustScore) error {
+	return txUpdateTrustScore(ctx, t.tx, did, update)
+}
+
+// RecordVerificationOutcome appends a VerificationOutcome record within
+// this transaction.
+func (t *Tx) RecordVerificationOutcome(ctx context.Context, did string, passed bool, latencyMs uint32) error {
+	return txRecordVerificationOutcome(ctx, t.tx, did, passed, latencyMs)
+}
+
+// GetClaimableTrustDelta computes the pending TrustScore state within this
+// transaction. See the one-shot GetClaimableTrustDelta method for semantics.
+func (t *Tx) GetClaimableTrustDelta(ctx context.Context, did string) (*domain.ClaimableTrustDelta, error) {
+	return txGetClaimableTrustDelta(ctx, t.tx, did)
+}
+
+// ClaimTrustDelta folds unclaimed VerificationOutcome records into the
+// TrustScore node within this transaction. See the one-shot ClaimTrustDelta
+// method for semantics.
+func (t *Tx) ClaimTrustDelta(ctx context.Context, did string, expectedVersion uint64) error {
+	return txClaimTrustDelta(ctx, t.tx, did, expectedVersion)
+}
+
+// GetHCWallet retrieves the HC wallet for an identity within this transaction.
+func (t *Tx) GetHCWallet(ctx context.Context, did string) (*domain.HCWallet, error) {
+	return txGetHCWallet(ctx, t.tx, did)
+}
+
+// LockHC locks HC for an escrow transaction within this transaction. See the
+// one-shot LockHC method for the idempotency-key and fixed-point semantics.
+func (t *Tx) LockHC(ctx context.Context, did string, amount decimal.Decimal, version uint64, idempotencyKey string) error {
+	return txLockHC(ctx, t.tx, did, amount, version, idempotencyKey)
+}
+
+// ReleaseHC releases locked HC within this transaction.
+func (t *Tx) ReleaseHC(ctx context.Context, did string, amount decimal.Decimal, idempotencyKey string) error {
+	return txReleaseHC(ctx, t.tx, did, amount, idempotencyKey)
+}
+
+// RefundHC refunds locked HC back to available balance within this transaction.
+func (t *Tx) RefundHC(ctx context.Context, did string, amount decimal.Decimal, idempotencyKey string) error {
+	return txRefundHC(ctx, t.tx, did, amount, idempotencyKey)
+}
+
+// CreditHC adds HC to a wallet within this transaction.
+func (t *Tx) CreditHC(ctx context.Context, did string, amount decimal.Decimal, idempotencyKey string) error {
+	return txCreditHC(ctx, t.tx, did, amount, idempotencyKey)
+}
+
+// GetWalletHistory returns HCTransfer ledger entries within this transaction.
+// See the one-shot GetWalletHistory method for the cursor and pageSize
+// semantics.
+func (t *Tx) GetWalletHistory(ctx context.Context, did string, cursor uint64, pageSize int) ([]domain.Transfer, uint64, error) {
+	return txGetWalletHistory(ctx, t.tx, did, cursor, pageSize)
+}
+
+// GetBalanceAt reconstructs a wallet's balance as of a point in time within
+// this transaction.
+func (t *Tx) GetBalanceAt(ctx context.Context, did string, at time.Time) (decimal.Decimal, error) {
+	return txGetBalanceAt(ctx, t.tx, did, at)
+}
+
+// GetAgentLineage retrieves the full lineage of an agent within this transaction.
+func (t *Tx) GetAgentLineage(ctx context.Context, did string) (*domain.AgentLineage, error) {
+	return txGetAgentLineage(ctx, t.tx, did)
+}
+
+// GetSpawnedAgents retrieves all agents spawned by an identity within this transaction.
+func (t *Tx) GetSpawnedAgents(ctx context.Context, did string) ([]domain.UnifiedID, error) {
+	return txGetSpawnedAgents(ctx, t.tx, did)
+}
+
+// GetDescendants retrieves the DIDs of rootDID and everything it has spawned
+// within this transaction.
+func (t *Tx) GetDescendants(ctx context.Context, rootDID string) ([]string, error) {
+	return txGetDescendants(ctx, t.tx, rootDID)
+}
+
+// CreateIdentity creates a new UnifiedID in the graph as a one-shot Tx.
+func (r *Neo4jRepository) CreateIdentity(ctx context.Context, id *domain.UnifiedID) error {
+	ctx = withDID(ctx, id.DID)
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := tx.CreateIdentity(ctx, id); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func txCreateIdentity(ctx context.Context, tx neo4j.ManagedTransaction, id *domain.UnifiedID) error {
+	query := `
+		CREATE (i:Identity {
+			did: $did,
+			entity_type: $entity_type,
+			created_at: datetime($created_at),
+			public_key: $public_key,
+			key_type: $key_type,
+			derivation_index: $derivation_index
+		})
+		WITH i
+		// If parent exists, create SPAWNED relationship
+		OPTIONAL MATCH (parent:Identity {did: $parent_did})
+		FOREACH (p IN CASE WHEN parent IS NOT NULL THEN [parent] ELSE [] END |
+			CREATE (p)-[:SPAWNED {created_at: datetime($created_at)}]->(i)
+		)
+		// Create initial trust score
+		CREATE (i)-[:HAS_TRUST]->(t:TrustScore {
+			score: 500,
+			verification_score: 200,
+			dispute_penalty: 0,
+			sla_score: 100,
+			network_score: 200,
+			updated_at: datetime($created_at),
+			verified_outcomes: 0,
+			dispute_rate: 0.0,
+			version: 1
+		})
+		// Create HC wallet
+		CREATE (i)-[:OWNS_WALLET]->(w:HCWallet {
+			available: '0',
+			locked: '0',
+			expires_at: datetime() + duration('P30D'),
+			version: 1,
+			updated_at: datetime($created_at)
+		})
+		RETURN i.did
+	`
+	params := map[string]any{
+		"did":              id.DID,
+		"entity_type":      id.EntityType.String(),
+		"created_at":       id.CreatedAt.Format(time.RFC3339),
+		"public_key":       id.PublicKey,
+		"key_type":         id.KeyType.String(),
+		"derivation_index": int64(id.DerivationIndex),
+		"parent_did":       nil,
+	}
+	if id.ParentDID != nil {
+		params["parent_did"] = *id.ParentDID
+	}
+
+	result, err := tx.Run(ctx, query, params)
+	if err != nil {
+		return err
+	}
+	_, err = result.Consume(ctx)
+	return err
+}
+
+// GetIdentity retrieves a UnifiedID by DID
+func (r *Neo4jRepository) GetIdentity(ctx context.Context, did string) (*domain.UnifiedID, error) {
+	ctx = withDID(ctx, did)
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txGetIdentity(ctx, tx, did)
+		})
 	})
 
-	return err
-}
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("identity not found: %s", did)
+	}
+	return result.(*domain.UnifiedID), nil
+}
+
+func txGetIdentity(ctx context.Context, tx neo4j.ManagedTransaction, did string) (*domain.UnifiedID, error) {
+	query := `
+		MATCH (i:Identity {did: $did})
+		OPTIONAL MATCH (parent:Identity)-[:SPAWNED]->(i)
+		RETURN i.did as did,
+			   i.entity_type as entity_type,
+			   i.created_at as created_at,
+			   i.public_key as public_key,
+			   parent.did as parent_did,
+			   i.key_type as key_type,
+			   i.derivation_index as derivation_index
+	`
+	result, err := tx.Run(ctx, query, map[string]any{"did": did})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Next(ctx) {
+		record := result.Record()
+		id := &domain.UnifiedID{
+			DID:       record.Values[0].(string),
+			PublicKey: record.Values[3].([]byte),
+		}
+
+		// Parse entity type
+		switch record.Values[1].(string) {
+		case "human":
+			id.EntityType = domain.EntityTypeHuman
+		case "agent":
+			id.EntityType = domain.EntityTypeAgent
+		case "organization":
+			id.EntityType = domain.EntityTypeOrganization
+		}
+
+		// Parse created_at
+		if createdAt, ok := record.Values[2].(neo4j.LocalDateTime); ok {
+			id.CreatedAt = createdAt.Time()
+		}
+
+		// Parse parent_did
+		if parentDID, ok := record.Values[4].(string); ok {
+			id.ParentDID = &parentDID
+		}
+
+		// Parse key type
+		if keyType, ok := record.Values[5].(string); ok {
+			id.KeyType = parseKeyType(keyType)
+		}
+
+		// Parse derivation index
+		if derivationIndex, ok := record.Values[6].(int64); ok {
+			id.DerivationIndex = uint64(derivationIndex)
+		}
+
+		return id, nil
+	}
+
+	return nil, nil
+}
+
+// parseKeyType parses the string form of domain.KeyType stored on Identity
+// nodes back into its enum value.
+func parseKeyType(s string) domain.KeyType {
+	switch s {
+	case "Ed25519":
+		return domain.KeyTypeEd25519
+	case "secp256k1":
+		return domain.KeyTypeSecp256k1
+	case "P-256":
+		return domain.KeyTypeP256
+	default:
+		return domain.KeyTypeUnspecified
+	}
+}
+
+// GetTrustScore retrieves the trust score for an identity, first folding in
+// any verification outcomes accrued since the last claim so every caller --
+// not just the public API path -- sees the claimed score rather than one
+// that's stale purely because nothing happened to claim it. The claim is
+// best-effort: if it loses a race with a concurrent claim, the already-fetched
+// (and still accurate as of a moment ago) score is returned rather than
+// failing the read.
+func (r *Neo4jRepository) GetTrustScore(ctx context.Context, did string) (*domain.TrustScore, error) {
+	trust, err := r.getTrustScore(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	if trust == nil {
+		return nil, nil
+	}
+
+	if err := r.ClaimTrustDelta(ctx, did, trust.Version); err != nil {
+		return trust, nil
+	}
+	return r.getTrustScore(ctx, did)
+}
+
+// getTrustScore is the raw, non-claiming read GetTrustScore and
+// ClaimTrustDelta are both built on.
+func (r *Neo4jRepository) getTrustScore(ctx context.Context, did string) (*domain.TrustScore, error) {
+	ctx = withDID(ctx, did)
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txGetTrustScore(ctx, tx, did)
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("trust score not found for: %s", did)
+	}
+	return result.(*domain.TrustScore), nil
+}
+
+func txGetTrustScore(ctx context.Context, tx neo4j.ManagedTransaction, did string) (*domain.TrustScore, error) {
+	query := `
+		MATCH (i:Identity {did: $did})-[:HAS_TRUST]->(t:TrustScore)
+		RETURN t.score as score,
+			   t.verification_score as verification_score,
+			   t.dispute_penalty as dispute_penalty,
+			   t.sla_score as sla_score,
+			   t.network_score as network_score,
+			   t.updated_at as updated_at,
+			   t.verified_outcomes as verified_outcomes,
+			   t.dispute_rate as dispute_rate,
+			   t.version as version
+	`
+	result, err := tx.Run(ctx, query, map[string]any{"did": did})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Next(ctx) {
+		record := result.Record()
+		trust := &domain.TrustScore{
+			Score: uint32(record.Values[0].(int64)),
+			Components: domain.TrustComponents{
+				VerificationScore: uint32(record.Values[1].(int64)),
+				DisputePenalty:    uint32(record.Values[2].(int64)),
+				SLAScore:          uint32(record.Values[3].(int64)),
+				NetworkScore:      uint32(record.Values[4].(int64)),
+			},
+			VerifiedOutcomes: uint64(record.Values[6].(int64)),
+			DisputeRate:      record.Values[7].(float64),
+			Version:          uint64(record.Values[8].(int64)),
+		}
+
+		if updatedAt, ok := record.Values[5].(neo4j.LocalDateTime); ok {
+			trust.UpdatedAt = updatedAt.Time()
+		}
+
+		return trust, nil
+	}
+
+	return nil, nil
+}
+
+// UpdateTrustScore updates the trust score for an identity as a one-shot Tx.
+func (r *Neo4jRepository) UpdateTrustScore(ctx context.Context, did string, update *domain.TrustScore) error {
+	ctx = withDID(ctx, did)
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := tx.UpdateTrustScore(ctx, did, update); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func txUpdateTrustScore(ctx context.Context, tx neo4j.ManagedTransaction, did string, update *domain.TrustScore) error {
+	query := `
+		MATCH (i:Identity {did: $did})-[:HAS_TRUST]->(t:TrustScore)
+		WHERE t.version = $expected_version
+		SET t.score = $score,
+			t.verification_score = $verification_score,
+			t.dispute_penalty = $dispute_penalty,
+			t.sla_score = $sla_score,
+			t.network_score = $network_score,
+			t.updated_at = datetime(),
+			t.verified_outcomes = $verified_outcomes,
+			t.dispute_rate = $dispute_rate,
+			t.version = t.version + 1
+		RETURN t.version as new_version
+	`
+	params := map[string]any{
+		"did":                did,
+		"expected_version":   update.Version,
+		"score":              update.Score,
+		"verification_score": update.Components.VerificationScore,
+		"dispute_penalty":    update.Components.DisputePenalty,
+		"sla_score":          update.Components.SLAScore,
+		"network_score":      update.Components.NetworkScore,
+		"verified_outcomes":  update.VerifiedOutcomes,
+		"dispute_rate":       update.DisputeRate,
+	}
+
+	result, err := tx.Run(ctx, query, params)
+	if err != nil {
+		return err
+	}
+
+	if !result.Next(ctx) {
+		return fmt.Errorf("concurrent modification detected for trust score: %s", did)
+	}
+
+	_, err = result.Consume(ctx)
+	return err
+}
+
+// RecordVerificationOutcome appends a VerificationOutcome record for did as
+// a one-shot Tx. It does not touch the TrustScore node - see
+// GetClaimableTrustDelta and ClaimTrustDelta for how outcomes are folded in.
+func (r *Neo4jRepository) RecordVerificationOutcome(ctx context.Context, did string, passed bool, latencyMs uint32) error {
+	ctx = withDID(ctx, did)
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := tx.RecordVerificationOutcome(ctx, did, passed, latencyMs); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// txRecordVerificationOutcome creates an append-only VerificationOutcome
+// node instead of mutating TrustScore directly. Writing the aggregate score
+// on every outcome turns it into a hot, contended node under load; appending
+// here and folding outcomes in lazily via ClaimTrustDelta trades that for a
+// cheap, lock-free write plus an audit trail of what produced each score
+// change.
+func txRecordVerificationOutcome(ctx context.Context, tx neo4j.ManagedTransaction, did string, passed bool, latencyMs uint32) error {
+	query := `
+		MATCH (i:Identity {did: $did})
+		CREATE (i)-[:HAS_OUTCOME]->(:VerificationOutcome {
+			id: $id,
+			did: $did,
+			passed: $passed,
+			latency_ms: $latency_ms,
+			recorded_at: datetime(),
+			epoch: $epoch,
+			claimed_at: null
+		})
+	`
+	result, err := tx.Run(ctx, query, map[string]any{
+		"id":         uuid.NewString(),
+		"did":        did,
+		"passed":     passed,
+		"latency_ms": latencyMs,
+		"epoch":      time.Now().Unix() / int64((24 * time.Hour).Seconds()),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = result.Consume(ctx)
+	return err
+}
+
+// GetClaimableTrustDelta computes the TrustScore state that would result
+// from folding in every unclaimed VerificationOutcome for did, without
+// writing anything.
+func (r *Neo4jRepository) GetClaimableTrustDelta(ctx context.Context, did string) (*domain.ClaimableTrustDelta, error) {
+	ctx = withDID(ctx, did)
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txGetClaimableTrustDelta(ctx, tx, did)
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return result.(*domain.ClaimableTrustDelta), nil
+}
+
+// txGetClaimableTrustDelta replays unclaimed outcomes, oldest first, against
+// the current TrustScore using the same +1/-2 verification and +1/-1 SLA
+// rules RecordVerificationOutcome used to apply inline, so the projected
+// result matches exactly what immediate mutation would have produced.
+func txGetClaimableTrustDelta(ctx context.Context, tx neo4j.ManagedTransaction, did string) (*domain.ClaimableTrustDelta, error) {
+	delta, _, err := txComputeClaimableTrustDelta(ctx, tx, did)
+	return delta, err
+}
+
+// txComputeClaimableTrustDelta is txGetClaimableTrustDelta's implementation,
+// additionally returning the ids of the outcomes it replayed. txClaimTrustDelta
+// needs those ids so it can mark claimed exactly the outcomes this delta was
+// computed from, rather than re-matching "unclaimed" after the fact and
+// risking outcomes recorded in between.
+func txComputeClaimableTrustDelta(ctx context.Context, tx neo4j.ManagedTransaction, did string) (*domain.ClaimableTrustDelta, []string, error) {
+	trust, err := txGetTrustScore(ctx, tx, did)
+	if err != nil {
+		return nil, nil, err
+	}
+	if trust == nil {
+		return nil, nil, fmt.Errorf("trust score not found for: %s", did)
+	}
+
+	result, err := tx.Run(ctx, `
+		MATCH (i:Identity {did: $did})-[:HAS_OUTCOME]->(o:VerificationOutcome)
+		WHERE o.claimed_at IS NULL
+		RETURN o.id as id, o.passed as passed, o.latency_ms as latency_ms
+		ORDER BY o.recorded_at ASC
+	`, map[string]any{"did": did})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	verification := int64(trust.Components.VerificationScore)
+	sla := int64(trust.Components.SLAScore)
+	verifiedOutcomes := trust.VerifiedOutcomes
+	var pending uint64
+	var outcomeIDs []string
+
+	for result.Next(ctx) {
+		record := result.Record()
+		id, _ := record.Values[0].(string)
+		passed, _ := record.Values[1].(bool)
+		latencyMs, _ := record.Values[2].(int64)
+
+		verifiedOutcomes++
+		if passed {
+			if verification < 400 {
+				verification++
+			}
+		} else if verification > 0 {
+			verification -= 2
+			if verification < 0 {
+				verification = 0
+			}
+		}
+
+		if latencyMs <= 2000 {
+			if sla < 200 {
+				sla++
+			}
+		} else if sla > 0 {
+			sla--
+		}
+
+		pending++
+		outcomeIDs = append(outcomeIDs, id)
+	}
+
+	score := verification + sla + int64(trust.Components.NetworkScore) - int64(trust.Components.DisputePenalty)
+	if score < 0 {
+		score = 0
+	}
+
+	return &domain.ClaimableTrustDelta{
+		VerificationScore: uint32(verification),
+		SLAScore:          uint32(sla),
+		Score:             uint32(score),
+		VerifiedOutcomes:  verifiedOutcomes,
+		PendingOutcomes:   pending,
+	}, outcomeIDs, nil
+}
+
+// ClaimTrustDelta folds every unclaimed VerificationOutcome for did into its
+// TrustScore node and marks those outcomes claimed, gated on expectedVersion
+// to guard against a concurrent claim or score update. It is a one-shot Tx;
+// a no-op (returns nil) if there is nothing unclaimed to fold in.
+func (r *Neo4jRepository) ClaimTrustDelta(ctx context.Context, did string, expectedVersion uint64) error {
+	ctx = withDID(ctx, did)
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := tx.ClaimTrustDelta(ctx, did, expectedVersion); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func txClaimTrustDelta(ctx context.Context, tx neo4j.ManagedTransaction, did string, expectedVersion uint64) error {
+	delta, outcomeIDs, err := txComputeClaimableTrustDelta(ctx, tx, did)
+	if err != nil {
+		return err
+	}
+	if delta.PendingOutcomes == 0 {
+		return nil
+	}
+
+	// Marking claimed is scoped to outcomeIDs (exactly what delta was computed
+	// from) rather than a fresh "WHERE o.claimed_at IS NULL" match. Re-matching
+	// unclaimed here would also sweep up any outcome recorded after the read
+	// above, marking it claimed without its score ever being folded in.
+	result, err := tx.Run(ctx, `
+		MATCH (i:Identity {did: $did})-[:HAS_TRUST]->(t:TrustScore)
+		WHERE t.version = $expected_version
+		SET t.verification_score = $verification_score,
+			t.sla_score = $sla_score,
+			t.score = $score,
+			t.verified_outcomes = $verified_outcomes,
+			t.updated_at = datetime(),
+			t.version = t.version + 1
+		WITH i, t
+		MATCH (i)-[:HAS_OUTCOME]->(o:VerificationOutcome)
+		WHERE o.id IN $outcome_ids
+		SET o.claimed_at = datetime()
+		RETURN t.version as new_version, count(o) as claimed_count
+	`, map[string]any{
+		"did":                did,
+		"expected_version":   expectedVersion,
+		"verification_score": delta.VerificationScore,
+		"sla_score":          delta.SLAScore,
+		"score":              delta.Score,
+		"verified_outcomes":  delta.VerifiedOutcomes,
+		"outcome_ids":        outcomeIDs,
+	})
+	if err != nil {
+		return err
+	}
+	if !result.Next(ctx) {
+		return fmt.Errorf("concurrent modification detected for trust score: %s", did)
+	}
+	_, err = result.Consume(ctx)
+	return err
+}
+
+// GetHCWallet retrieves the HC wallet for an identity
+func (r *Neo4jRepository) GetHCWallet(ctx context.Context, did string) (*domain.HCWallet, error) {
+	ctx = withDID(ctx, did)
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txGetHCWallet(ctx, tx, did)
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("wallet not found for: %s", did)
+	}
+	return result.(*domain.HCWallet), nil
+}
+
+func txGetHCWallet(ctx context.Context, tx neo4j.ManagedTransaction, did string) (*domain.HCWallet, error) {
+	query := `
+		MATCH (i:Identity {did: $did})-[:OWNS_WALLET]->(w:HCWallet)
+		RETURN w.available as available,
+			   w.locked as locked,
+			   w.expires_at as expires_at,
+			   w.version as version,
+			   w.updated_at as updated_at
+	`
+	result, err := tx.Run(ctx, query, map[string]any{"did": did})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Next(ctx) {
+		record := result.Record()
+
+		available := minorUnitsToDecimal(parseMinorUnits(record.Values[0].(string)))
+		locked := minorUnitsToDecimal(parseMinorUnits(record.Values[1].(string)))
 
-// GetIdentity retrieves a UnifiedID by DID
-func (r *Neo4jRepository) GetIdentity(ctx context.Context, did string) (*domain.UnifiedID, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
-	defer session.Close(ctx)
-
-	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		query := `
-			MATCH (i:Identity {did: $did})
-			OPTIONAL MATCH (parent:Identity)-[:SPAWNED]->(i)
-			RETURN i.did as did,
-				   i.entity_type as entity_type,
-				   i.created_at as created_at,
-				   i.public_key as public_key,
-				   parent.did as parent_did
-		`
-		result, err := tx.Run(ctx, query, map[string]any{"did": did})
-		if err != nil {
-			return nil, err
+		wallet := &domain.HCWallet{
+			OwnerDID:  did,
+			Available: available,
+			Locked:    locked,
+			Version:   uint64(record.Values[3].(int64)),
 		}
 
-		if result.Next(ctx) {
-			record := result.Record()
-			id := &domain.UnifiedID{
-				DID:       record.Values[0].(string),
-				PublicKey: record.Values[3].([]byte),
-			}
-
-			// Parse entity type
-			switch record.Values[1].(string) {
-			case "human":
-				id.EntityType = domain.EntityTypeHuman
-			case "agent":
-				id.EntityType = domain.EntityTypeAgent
-			case "organization":
-				id.EntityType = domain.EntityTypeOrganization
-			}
+		if expiresAt, ok := record.Values[2].(neo4j.LocalDateTime); ok {
+			wallet.ExpiresAt = expiresAt.Time()
+		}
+		if updatedAt, ok := record.Values[4].(neo4j.LocalDateTime); ok {
+			wallet.UpdatedAt = updatedAt.Time()
+		}
 
-			// Parse created_at
-			if createdAt, ok := record.Values[2].(neo4j.LocalDateTime); ok {
-				id.CreatedAt = createdAt.Time()
-			}
+		return wallet, nil
+	}
 
-			// Parse parent_did
-			if parentDID, ok := record.Values[4].(string); ok {
-				id.ParentDID = &parentDID
-			}
+	return nil, nil
+}
 
-			return id, nil
-		}
+// walletSnapshot is the current on-graph state of an HCWallet read inside a
+// transaction, in minor units, before an idempotent mutation is applied.
+type walletSnapshot struct {
+	available *big.Int
+	locked    *big.Int
+	version   uint64
+	expiresAt time.Time
+}
 
-		return nil, nil
-	})
+// mergeOperation records (or looks up) an HCOperation idempotency node for
+// key and reports whether this call created it. A false return means an
+// identical key was already applied, so the caller should skip its mutation
+// and treat the call as a successful no-op.
+func mergeOperation(ctx context.Context, tx neo4j.ManagedTransaction, did, kind, amount, key string) (bool, error) {
+	result, err := tx.Run(ctx, `
+		MERGE (op:HCOperation {key: $key})
+		ON CREATE SET op.did = $did, op.kind = $kind, op.amount = $amount, op.applied_at = datetime(), op.is_new = true
+		ON MATCH SET op.is_new = false
+		RETURN op.is_new as was_new
+	`, map[string]any{"key": key, "did": did, "kind": kind, "amount": amount})
+	if err != nil {
+		return false, err
+	}
+	if !result.Next(ctx) {
+		return false, fmt.Errorf("failed to record idempotency operation")
+	}
+	wasNew, _ := result.Record().Values[0].(bool)
+	return wasNew, nil
+}
 
+// readWalletForUpdate loads the current wallet state within tx, in minor
+// units, so the caller can compute the new balance in Go under the version
+// guard rather than relying on Cypher's float arithmetic.
+func readWalletForUpdate(ctx context.Context, tx neo4j.ManagedTransaction, did string) (*walletSnapshot, error) {
+	result, err := tx.Run(ctx, `
+		MATCH (i:Identity {did: $did})-[:OWNS_WALLET]->(w:HCWallet)
+		RETURN w.available as available, w.locked as locked, w.version as version, w.expires_at as expires_at
+	`, map[string]any{"did": did})
 	if err != nil {
 		return nil, err
 	}
-	if result == nil {
-		return nil, fmt.Errorf("identity not found: %s", did)
+	if !result.Next(ctx) {
+		return nil, fmt.Errorf("wallet not found for: %s", did)
 	}
-	return result.(*domain.UnifiedID), nil
+
+	record := result.Record()
+	snapshot := &walletSnapshot{
+		available: parseMinorUnits(record.Values[0].(string)),
+		locked:    parseMinorUnits(record.Values[1].(string)),
+		version:   uint64(record.Values[2].(int64)),
+	}
+	if expiresAt, ok := record.Values[3].(neo4j.LocalDateTime); ok {
+		snapshot.expiresAt = expiresAt.Time()
+	}
+	return snapshot, nil
 }
 
-// GetTrustScore retrieves the trust score for an identity
-func (r *Neo4jRepository) GetTrustScore(ctx context.Context, did string) (*domain.TrustScore, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
-	defer session.Close(ctx)
-
-	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		query := `
-			MATCH (i:Identity {did: $did})-[:HAS_TRUST]->(t:TrustScore)
-			RETURN t.score as score,
-				   t.verification_score as verification_score,
-				   t.dispute_penalty as dispute_penalty,
-				   t.sla_score as sla_score,
-				   t.network_score as network_score,
-				   t.updated_at as updated_at,
-				   t.verified_outcomes as verified_outcomes,
-				   t.dispute_rate as dispute_rate,
-				   t.version as version
-		`
-		result, err := tx.Run(ctx, query, map[string]any{"did": did})
-		if err != nil {
-			return nil, err
-		}
+// LockHC locks HC for an escrow transaction as a one-shot Tx. idempotencyKey
+// de-duplicates retries of the same logical request: a repeated key for an
+// operation that already applied is a no-op that returns success without
+// re-checking the balance, rather than double-locking funds.
+//
+// Balances are read and the new values computed in Go as arbitrary-precision
+// integers (minor units), then written back gated on the version the read
+// observed - this avoids the precision loss toFloat()/toString() round trips
+// through Cypher would introduce for amounts with more than ~15 significant
+// decimal digits.
+func (r *Neo4jRepository) LockHC(ctx context.Context, did string, amount decimal.Decimal, version uint64, idempotencyKey string) error {
+	ctx = withDID(ctx, did)
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := tx.LockHC(ctx, did, amount, version, idempotencyKey); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
 
-		if result.Next(ctx) {
-			record := result.Record()
-			trust := &domain.TrustScore{
-				Score: uint32(record.Values[0].(int64)),
-				Components: domain.TrustComponents{
-					VerificationScore: uint32(record.Values[1].(int64)),
-					DisputePenalty:    uint32(record.Values[2].(int64)),
-					SLAScore:          uint32(record.Values[3].(int64)),
-					NetworkScore:      uint32(record.Values[4].(int64)),
-				},
-				VerifiedOutcomes: uint64(record.Values[6].(int64)),
-				DisputeRate:      record.Values[7].(float64),
-				Version:          uint64(record.Values[8].(int64)),
-			}
+func txLockHC(ctx context.Context, tx neo4j.ManagedTransaction, did string, amount decimal.Decimal, version uint64, idempotencyKey string) error {
+	amountMinor := decimalToMinorUnits(amount)
 
-			if updatedAt, ok := record.Values[5].(neo4j.LocalDateTime); ok {
-				trust.UpdatedAt = updatedAt.Time()
-			}
+	isNew, err := mergeOperation(ctx, tx, did, "lock", amount.String(), idempotencyKey)
+	if err != nil {
+		return err
+	}
+	if !isNew {
+		return nil
+	}
 
-			return trust, nil
-		}
+	wallet, err := readWalletForUpdate(ctx, tx, did)
+	if err != nil {
+		return err
+	}
+	if wallet.version != version {
+		return fmt.Errorf("insufficient balance or concurrent modification")
+	}
+	if wallet.available.Cmp(amountMinor) < 0 {
+		return fmt.Errorf("insufficient balance or concurrent modification")
+	}
+	if !wallet.expiresAt.IsZero() && !wallet.expiresAt.After(time.Now()) {
+		return fmt.Errorf("insufficient balance or concurrent modification")
+	}
 
-		return nil, nil
+	newAvailable := new(big.Int).Sub(wallet.available, amountMinor)
+	newLocked := new(big.Int).Add(wallet.locked, amountMinor)
+
+	result, err := tx.Run(ctx, `
+		MATCH (i:Identity {did: $did})-[:OWNS_WALLET]->(w:HCWallet)
+		WHERE w.version = $expected_version
+		SET w.available = $new_available,
+			w.locked = $new_locked,
+			w.updated_at = datetime(),
+			w.version = w.version + 1
+		CREATE (w)<-[:AFFECTS]-(:HCTransfer {
+			id: $transfer_id, from_did: $did, to_did: '', amount: $amount_str,
+			kind: 'lock', block_index: w.version, created_at: datetime()
+		})
+		RETURN w.version as new_version
+	`, map[string]any{
+		"did":              did,
+		"expected_version": version,
+		"new_available":    newAvailable.String(),
+		"new_locked":       newLocked.String(),
+		"amount_str":       amount.String(),
+		"transfer_id":      uuid.NewString(),
 	})
+	if err != nil {
+		return err
+	}
+	if !result.Next(ctx) {
+		return fmt.Errorf("insufficient balance or concurrent modification")
+	}
+	_, err = result.Consume(ctx)
+	return err
+}
 
+// ReleaseHC releases locked HC after successful transaction as a one-shot Tx.
+// See LockHC for the idempotency-key semantics and fixed-point representation.
+func (r *Neo4jRepository) ReleaseHC(ctx context.Context, did string, amount decimal.Decimal, idempotencyKey string) error {
+	ctx = withDID(ctx, did)
+	tx, err := r.Begin(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	if result == nil {
-		return nil, fmt.Errorf("trust score not found for: %s", did)
+	if err := tx.ReleaseHC(ctx, did, amount, idempotencyKey); err != nil {
+		tx.Rollback(ctx)
+		return err
 	}
-	return result.(*domain.TrustScore), nil
+	return tx.Commit(ctx)
 }
 
-// UpdateTrustScore updates the trust score for an identity
-func (r *Neo4jRepository) UpdateTrustScore(ctx context.Context, did string, update *domain.TrustScore) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
-	defer session.Close(ctx)
-
-	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		// Use optimistic locking with version check
-		query := `
-			MATCH (i:Identity {did: $did})-[:HAS_TRUST]->(t:TrustScore)
-			WHERE t.version = $expected_version
-			SET t.score = $score,
-				t.verification_score = $verification_score,
-				t.dispute_penalty = $dispute_penalty,
-				t.sla_score = $sla_score,
-				t.network_score = $network_score,
-				t.updated_at = datetime(),
-				t.verified_outcomes = $verified_outcomes,
-				t.dispute_rate = $dispute_rate,
-				t.version = t.version + 1
-			RETURN t.version as new_version
-		`
-		params := map[string]any{
-			"did":                did,
-			"expected_version":   update.Version,
-			"score":              update.Score,
-			"verification_score": update.Components.VerificationScore,
-			"dispute_penalty":    update.Components.DisputePenalty,
-			"sla_score":          update.Components.SLAScore,
-			"network_score":      update.Components.NetworkScore,
-			"verified_outcomes":  update.VerifiedOutcomes,
-			"dispute_rate":       update.DisputeRate,
-		}
-
-		result, err := tx.Run(ctx, query, params)
-		if err != nil {
-			return nil, err
-		}
+func txReleaseHC(ctx context.Context, tx neo4j.ManagedTransaction, did string, amount decimal.Decimal, idempotencyKey string) error {
+	amountMinor := decimalToMinorUnits(amount)
 
-		if !result.Next(ctx) {
-			return nil, fmt.Errorf("concurrent modification detected for trust score: %s", did)
-		}
+	isNew, err := mergeOperation(ctx, tx, did, "release", amount.String(), idempotencyKey)
+	if err != nil {
+		return err
+	}
+	if !isNew {
+		return nil
+	}
 
-		return result.Consume(ctx)
-	})
+	wallet, err := readWalletForUpdate(ctx, tx, did)
+	if err != nil {
+		return err
+	}
+	if wallet.locked.Cmp(amountMinor) < 0 {
+		return fmt.Errorf("insufficient locked balance")
+	}
 
+	newLocked := new(big.Int).Sub(wallet.locked, amountMinor)
+
+	result, err := tx.Run(ctx, `
+		MATCH (i:Identity {did: $did})-[:OWNS_WALLET]->(w:HCWallet)
+		WHERE w.version = $expected_version
+		SET w.locked = $new_locked,
+			w.updated_at = datetime(),
+			w.version = w.version + 1
+		CREATE (w)<-[:AFFECTS]-(:HCTransfer {
+			id: $transfer_id, from_did: $did, to_did: '', amount: $amount_str,
+			kind: 'release', block_index: w.version, created_at: datetime()
+		})
+		RETURN w.version
+	`, map[string]any{
+		"did":              did,
+		"expected_version": wallet.version,
+		"new_locked":       newLocked.String(),
+		"amount_str":       amount.String(),
+		"transfer_id":      uuid.NewString(),
+	})
+	if err != nil {
+		return err
+	}
+	if !result.Next(ctx) {
+		return fmt.Errorf("concurrent modification detected for wallet: %s", did)
+	}
+	_, err = result.Consume(ctx)
 	return err
 }
 
-// RecordVerificationOutcome updates trust score based on verification result
-func (r *Neo4jRepository) RecordVerificationOutcome(ctx context.Context, did string, passed bool, latencyMs uint32) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
-	defer session.Close(ctx)
-
-	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		query := `
-			MATCH (i:Identity {did: $did})-[:HAS_TRUST]->(t:TrustScore)
-			SET t.verified_outcomes = t.verified_outcomes + 1,
-				t.updated_at = datetime(),
-				t.version = t.version + 1,
-				// Adjust verification score based on outcome
-				t.verification_score = CASE
-					WHEN $passed THEN CASE WHEN t.verification_score < 400 THEN t.verification_score + 1 ELSE 400 END
-					ELSE CASE WHEN t.verification_score > 0 THEN t.verification_score - 2 ELSE 0 END
-				END,
-				// Adjust SLA score based on latency (target: 2000ms)
-				t.sla_score = CASE
-					WHEN $latency_ms <= 2000 THEN CASE WHEN t.sla_score < 200 THEN t.sla_score + 1 ELSE 200 END
-					ELSE CASE WHEN t.sla_score > 0 THEN t.sla_score - 1 ELSE 0 END
-				END,
-				// Recalculate total score
-				t.score = t.verification_score + t.sla_score + t.network_score - t.dispute_penalty
-			RETURN t.score
-		`
-		result, err := tx.Run(ctx, query, map[string]any{
-			"did":        did,
-			"passed":     passed,
-			"latency_ms": latencyMs,
+// RefundHC refunds locked HC back to available balance as a one-shot Tx. See
+// LockHC for the idempotency-key semantics and fixed-point representation.
+func (r *Neo4jRepository) RefundHC(ctx context.Context, did string, amount decimal.Decimal, idempotencyKey string) error {
+	ctx = withDID(ctx, did)
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := tx.RefundHC(ctx, did, amount, idempotencyKey); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func txRefundHC(ctx context.Context, tx neo4j.ManagedTransaction, did string, amount decimal.Decimal, idempotencyKey string) error {
+	amountMinor := decimalToMinorUnits(amount)
+
+	isNew, err := mergeOperation(ctx, tx, did, "refund", amount.String(), idempotencyKey)
+	if err != nil {
+		return err
+	}
+	if !isNew {
+		return nil
+	}
+
+	wallet, err := readWalletForUpdate(ctx, tx, did)
+	if err != nil {
+		return err
+	}
+	if wallet.locked.Cmp(amountMinor) < 0 {
+		return fmt.Errorf("insufficient locked balance")
+	}
+
+	newAvailable := new(big.Int).Add(wallet.available, amountMinor)
+	newLocked := new(big.Int).Sub(wallet.locked, amountMinor)
+
+	result, err := tx.Run(ctx, `
+		MATCH (i:Identity {did: $did})-[:OWNS_WALLET]->(w:HCWallet)
+		WHERE w.version = $expected_version
+		SET w.available = $new_available,
+			w.locked = $new_locked,
+			w.updated_at = datetime(),
+			w.version = w.version + 1
+		CREATE (w)<-[:AFFECTS]-(:HCTransfer {
+			id: $transfer_id, from_did: '', to_did: $did, amount: $amount_str,
+			kind: 'refund', block_index: w.version, created_at: datetime()
 		})
-		if err != nil {
-			return nil, err
-		}
-		return result.Consume(ctx)
+		RETURN w.version
+	`, map[string]any{
+		"did":              did,
+		"expected_version": wallet.version,
+		"new_available":    newAvailable.String(),
+		"new_locked":       newLocked.String(),
+		"amount_str":       amount.String(),
+		"transfer_id":      uuid.NewString(),
 	})
-
+	if err != nil {
+		return err
+	}
+	if !result.Next(ctx) {
+		return fmt.Errorf("concurrent modification detected for wallet: %s", did)
+	}
+	_, err = result.Consume(ctx)
 	return err
 }
 
-// GetHCWallet retrieves the HC wallet for an identity
-func (r *Neo4jRepository) GetHCWallet(ctx context.Context, did string) (*domain.HCWallet, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
-	defer session.Close(ctx)
-
-	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		query := `
-			MATCH (i:Identity {did: $did})-[:OWNS_WALLET]->(w:HCWallet)
-			RETURN w.available as available,
-				   w.locked as locked,
-				   w.expires_at as expires_at,
-				   w.version as version,
-				   w.updated_at as updated_at
-		`
-		result, err := tx.Run(ctx, query, map[string]any{"did": did})
-		if err != nil {
-			return nil, err
-		}
+// CreditHC adds HC to a wallet as a one-shot Tx. See LockHC for the
+// idempotency-key semantics and fixed-point representation.
+func (r *Neo4jRepository) CreditHC(ctx context.Context, did string, amount decimal.Decimal, idempotencyKey string) error {
+	ctx = withDID(ctx, did)
+	tx, err := r.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := tx.CreditHC(ctx, did, amount, idempotencyKey); err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
 
-		if result.Next(ctx) {
-			record := result.Record()
+func txCreditHC(ctx context.Context, tx neo4j.ManagedTransaction, did string, amount decimal.Decimal, idempotencyKey string) error {
+	amountMinor := decimalToMinorUnits(amount)
 
-			available, _ := decimal.NewFromString(record.Values[0].(string))
-			locked, _ := decimal.NewFromString(record.Values[1].(string))
+	isNew, err := mergeOperation(ctx, tx, did, "credit", amount.String(), idempotencyKey)
+	if err != nil {
+		return err
+	}
+	if !isNew {
+		return nil
+	}
 
-			wallet := &domain.HCWallet{
-				OwnerDID:  did,
-				Available: available,
-				Locked:    locked,
-				Version:   uint64(record.Values[3].(int64)),
-			}
+	wallet, err := readWalletForUpdate(ctx, tx, did)
+	if err != nil {
+		return err
+	}
 
-			if expiresAt, ok := record.Values[2].(neo4j.LocalDateTime); ok {
-				wallet.ExpiresAt = expiresAt.Time()
-			}
-			if updatedAt, ok := record.Values[4].(neo4j.LocalDateTime); ok {
-				wallet.UpdatedAt = updatedAt.Time()
-			}
+	newAvailable := new(big.Int).Add(wallet.available, amountMinor)
+
+	result, err := tx.Run(ctx, `
+		MATCH (i:Identity {did: $did})-[:OWNS_WALLET]->(w:HCWallet)
+		WHERE w.version = $expected_version
+		SET w.available = $new_available,
+			w.expires_at = CASE
+				WHEN w.expires_at < datetime() + duration('P30D')
+				THEN datetime() + duration('P30D')
+				ELSE w.expires_at
+			END,
+			w.updated_at = datetime(),
+			w.version = w.version + 1
+		CREATE (w)<-[:AFFECTS]-(:HCTransfer {
+			id: $transfer_id, from_did: '', to_did: $did, amount: $amount_str,
+			kind: 'credit', block_index: w.version, created_at: datetime()
+		})
+		RETURN w.available
+	`, map[string]any{
+		"did":              did,
+		"expected_version": wallet.version,
+		"new_available":    newAvailable.String(),
+		"amount_str":       amount.String(),
+		"transfer_id":      uuid.NewString(),
+	})
+	if err != nil {
+		return err
+	}
+	if !result.Next(ctx) {
+		return fmt.Errorf("concurrent modification detected for wallet: %s", did)
+	}
+	_, err = result.Consume(ctx)
+	return err
+}
 
-			return wallet, nil
-		}
+// maxWalletHistoryPageSize bounds how many HCTransfer entries a single
+// GetWalletHistory call can request, the same way maxListPageSize bounds
+// ListSpawnedAgents, so a wallet with a long ledger can't force one query to
+// buffer it in full.
+const maxWalletHistoryPageSize = 500
+
+// GetWalletHistory returns up to pageSize HCTransfer ledger entries for did
+// with a batch number greater than cursor, ordered oldest-first, plus the
+// cursor a caller should pass next time to resume after the last entry
+// returned. Because block_index is the wallet version at commit time, a
+// resuming caller only scans transfers newer than its cursor rather than
+// the full ledger. pageSize <= 0 or > maxWalletHistoryPageSize is clamped to
+// maxWalletHistoryPageSize.
+func (r *Neo4jRepository) GetWalletHistory(ctx context.Context, did string, cursor uint64, pageSize int) ([]domain.Transfer, uint64, error) {
+	ctx = withDID(ctx, did)
+	if pageSize <= 0 || pageSize > maxWalletHistoryPageSize {
+		pageSize = maxWalletHistoryPageSize
+	}
 
-		return nil, nil
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			transfers, nextCursor, err := txGetWalletHistory(ctx, tx, did, cursor, pageSize)
+			if err != nil {
+				return nil, err
+			}
+			return []any{transfers, nextCursor}, nil
+		})
 	})
 
 	if err != nil {
-		return nil, err
-	}
-	if result == nil {
-		return nil, fmt.Errorf("wallet not found for: %s", did)
+		return nil, 0, err
 	}
-	return result.(*domain.HCWallet), nil
+	pair := result.([]any)
+	return pair[0].([]domain.Transfer), pair[1].(uint64), nil
 }
 
-// LockHC locks HC for an escrow transaction
-func (r *Neo4jRepository) LockHC(ctx context.Context, did string, amount decimal.Decimal, version uint64) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
-	defer session.Close(ctx)
-
-	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		query := `
-			MATCH (i:Identity {did: $did})-[:OWNS_WALLET]->(w:HCWallet)
-			WHERE w.version = $expected_version
-			  AND toFloat(w.available) >= $amount
-			  AND w.expires_at > datetime()
-			SET w.available = toString(toFloat(w.available) - $amount),
-				w.locked = toString(toFloat(w.locked) + $amount),
-				w.updated_at = datetime(),
-				w.version = w.version + 1
-			RETURN w.version as new_version
-		`
-		amountFloat, _ := amount.Float64()
-		result, err := tx.Run(ctx, query, map[string]any{
-			"did":              did,
-			"expected_version": version,
-			"amount":           amountFloat,
-		})
-		if err != nil {
-			return nil, err
-		}
+func txGetWalletHistory(ctx context.Context, tx neo4j.ManagedTransaction, did string, cursor uint64, pageSize int) ([]domain.Transfer, uint64, error) {
+	query := `
+		MATCH (i:Identity {did: $did})-[:OWNS_WALLET]->(w:HCWallet)<-[:AFFECTS]-(t:HCTransfer)
+		WHERE t.block_index > $cursor
+		RETURN t.id as id, t.from_did as from_did, t.to_did as to_did,
+			   t.amount as amount, t.kind as kind, t.block_index as block_index,
+			   t.created_at as created_at
+		ORDER BY t.block_index ASC
+		LIMIT $limit
+	`
+	result, err := tx.Run(ctx, query, map[string]any{"did": did, "cursor": cursor, "limit": int64(pageSize)})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	transfers := []domain.Transfer{}
+	nextCursor := cursor
+	for result.Next(ctx) {
+		record := result.Record()
 
-		if !result.Next(ctx) {
-			return nil, fmt.Errorf("insufficient balance or concurrent modification")
+		amount, _ := decimal.NewFromString(record.Values[3].(string))
+		blockIndex := uint64(record.Values[5].(int64))
+
+		transfer := domain.Transfer{
+			FromDID:    record.Values[1].(string),
+			ToDID:      record.Values[2].(string),
+			Amount:     amount,
+			Kind:       record.Values[4].(string),
+			BlockIndex: blockIndex,
+		}
+		if id, ok := record.Values[0].(string); ok {
+			if parsed, err := uuid.Parse(id); err == nil {
+				transfer.ID = parsed
+			}
+		}
+		if createdAt, ok := record.Values[6].(neo4j.LocalDateTime); ok {
+			transfer.CreatedAt = createdAt.Time()
 		}
 
-		return result.Consume(ctx)
-	})
+		transfers = append(transfers, transfer)
+		if blockIndex > nextCursor {
+			nextCursor = blockIndex
+		}
+	}
 
-	return err
+	return transfers, nextCursor, nil
 }
 
-// ReleaseHC releases locked HC after successful transaction
-func (r *Neo4jRepository) ReleaseHC(ctx context.Context, did string, amount decimal.Decimal) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
-	defer session.Close(ctx)
-
-	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		query := `
-			MATCH (i:Identity {did: $did})-[:OWNS_WALLET]->(w:HCWallet)
-			WHERE toFloat(w.locked) >= $amount
-			SET w.locked = toString(toFloat(w.locked) - $amount),
-				w.updated_at = datetime(),
-				w.version = w.version + 1
-			RETURN w.version
-		`
-		amountFloat, _ := amount.Float64()
-		result, err := tx.Run(ctx, query, map[string]any{
-			"did":    did,
-			"amount": amountFloat,
+// GetBalanceAt reconstructs a wallet's total balance (available + locked) as
+// of time t by summing the signed HCTransfer ledger entries up to that
+// point: credits add funds, releases spend funds out of the wallet, and
+// lock/refund entries are internal moves between available and locked that
+// leave the total unchanged. A point-in-time balance needs every matching
+// entry, not just the most recent maxWalletHistoryPageSize of them the way
+// GetWalletHistory bounds its page, so txGetBalanceAt scans the same way
+// GetWalletHistory paginates -- one bounded batch at a time, ordered by
+// block_index -- accumulating across batches until the ledger up to t is
+// exhausted, rather than returning every row from a single unbounded query.
+func (r *Neo4jRepository) GetBalanceAt(ctx context.Context, did string, t time.Time) (decimal.Decimal, error) {
+	ctx = withDID(ctx, did)
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txGetBalanceAt(ctx, tx, did, t)
 		})
-		if err != nil {
-			return nil, err
-		}
-		return result.Consume(ctx)
 	})
 
-	return err
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return result.(decimal.Decimal), nil
 }
 
-// RefundHC refunds locked HC back to available balance
-func (r *Neo4jRepository) RefundHC(ctx context.Context, did string, amount decimal.Decimal) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
-	defer session.Close(ctx)
-
-	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		query := `
-			MATCH (i:Identity {did: $did})-[:OWNS_WALLET]->(w:HCWallet)
-			WHERE toFloat(w.locked) >= $amount
-			SET w.available = toString(toFloat(w.available) + $amount),
-				w.locked = toString(toFloat(w.locked) - $amount),
-				w.updated_at = datetime(),
-				w.version = w.version + 1
-			RETURN w.version
-		`
-		amountFloat, _ := amount.Float64()
+func txGetBalanceAt(ctx context.Context, tx neo4j.ManagedTransaction, did string, t time.Time) (decimal.Decimal, error) {
+	query := `
+		MATCH (i:Identity {did: $did})-[:OWNS_WALLET]->(w:HCWallet)<-[:AFFECTS]-(t:HCTransfer)
+		WHERE t.created_at <= datetime($at) AND t.block_index > $cursor
+		RETURN t.amount as amount, t.kind as kind, t.block_index as block_index
+		ORDER BY t.block_index ASC
+		LIMIT $limit
+	`
+
+	balance := decimal.Zero
+	cursor := uint64(0)
+	for {
 		result, err := tx.Run(ctx, query, map[string]any{
 			"did":    did,
-			"amount": amountFloat,
+			"at":     t.UTC().Format(time.RFC3339Nano),
+			"cursor": cursor,
+			"limit":  int64(maxWalletHistoryPageSize),
 		})
 		if err != nil {
-			return nil, err
+			return decimal.Zero, err
 		}
-		return result.Consume(ctx)
-	})
 
-	return err
+		rows := 0
+		for result.Next(ctx) {
+			record := result.Record()
+			amount, _ := decimal.NewFromString(record.Values[0].(string))
+			switch record.Values[1].(string) {
+			case domain.TransferKindCredit:
+				balance = balance.Add(amount)
+			case domain.TransferKindRelease:
+				balance = balance.Sub(amount)
+			}
+			if blockIndex := uint64(record.Values[2].(int64)); blockIndex > cursor {
+				cursor = blockIndex
+			}
+			rows++
+		}
+		if err := result.Err(); err != nil {
+			return decimal.Zero, err
+		}
+		if rows < maxWalletHistoryPageSize {
+			return balance, nil
+		}
+	}
 }
 
-// CreditHC adds HC to a wallet
-func (r *Neo4jRepository) CreditHC(ctx context.Context, did string, amount decimal.Decimal) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
-	defer session.Close(ctx)
-
-	_, err := session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		query := `
-			MATCH (i:Identity {did: $did})-[:OWNS_WALLET]->(w:HCWallet)
-			SET w.available = toString(toFloat(w.available) + $amount),
-				w.expires_at = CASE
-					WHEN w.expires_at < datetime() + duration('P30D')
-					THEN datetime() + duration('P30D')
-					ELSE w.expires_at
-				END,
-				w.updated_at = datetime(),
-				w.version = w.version + 1
-			RETURN w.available
-		`
-		amountFloat, _ := amount.Float64()
-		result, err := tx.Run(ctx, query, map[string]any{
-			"did":    did,
-			"amount": amountFloat,
+// PurgeExpiredOperations deletes HCOperation idempotency records older than
+// maxAge, bounding the graph growth from the append-only dedup ledger.
+// Operators should run this on a periodic sweep (e.g. daily, with a 7-day
+// maxAge) once the idempotency window for client retries has safely passed.
+func (r *Neo4jRepository) PurgeExpiredOperations(ctx context.Context, maxAge time.Duration) error {
+	_, err := r.withSession(ctx, neo4j.AccessModeWrite, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			query := `
+				MATCH (op:HCOperation)
+				WHERE op.applied_at < datetime() - duration({seconds: $max_age_seconds})
+				DETACH DELETE op
+			`
+			result, err := tx.Run(ctx, query, map[string]any{
+				"max_age_seconds": int64(maxAge.Seconds()),
+			})
+			if err != nil {
+				return nil, err
+			}
+			return result.Consume(ctx)
 		})
-		if err != nil {
-			return nil, err
-		}
-		return result.Consume(ctx)
 	})
 
 	return err
@@ -504,126 +1646,170 @@ func (r *Neo4jRepository) CreditHC(ctx context.Context, did string, amount decim
 
 // GetAgentLineage retrieves the full lineage of an agent
 func (r *Neo4jRepository) GetAgentLineage(ctx context.Context, did string) (*domain.AgentLineage, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
-	defer session.Close(ctx)
-
-	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		query := `
-			MATCH path = (root:Identity)-[:SPAWNED*0..]->(agent:Identity {did: $did})
-			WHERE NOT ()-[:SPAWNED]->(root)
-			WITH agent, root, nodes(path) as ancestors, length(path) as depth
-			OPTIONAL MATCH (parent:Identity)-[:SPAWNED]->(agent)
-			RETURN agent.did as did,
-				   parent.did as parent_did,
-				   root.did as root_did,
-				   depth,
-				   agent.created_at as spawned_at,
-				   [n in ancestors | n.did] as ancestor_dids
-		`
-		result, err := tx.Run(ctx, query, map[string]any{"did": did})
-		if err != nil {
-			return nil, err
-		}
+	ctx = withDID(ctx, did)
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txGetAgentLineage(ctx, tx, did)
+		})
+	})
 
-		if result.Next(ctx) {
-			record := result.Record()
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, fmt.Errorf("lineage not found for: %s", did)
+	}
+	return result.(*domain.AgentLineage), nil
+}
 
-			lineage := &domain.AgentLineage{
-				AgentDID: record.Values[0].(string),
-				RootDID:  record.Values[2].(string),
-				Depth:    int(record.Values[3].(int64)),
-			}
+func txGetAgentLineage(ctx context.Context, tx neo4j.ManagedTransaction, did string) (*domain.AgentLineage, error) {
+	query := `
+		MATCH path = (root:Identity)-[:SPAWNED*0..]->(agent:Identity {did: $did})
+		WHERE NOT ()-[:SPAWNED]->(root)
+		WITH agent, root, nodes(path) as ancestors, length(path) as depth
+		OPTIONAL MATCH (parent:Identity)-[:SPAWNED]->(agent)
+		RETURN agent.did as did,
+			   parent.did as parent_did,
+			   root.did as root_did,
+			   depth,
+			   agent.created_at as spawned_at,
+			   [n in ancestors | n.did] as ancestor_dids
+	`
+	result, err := tx.Run(ctx, query, map[string]any{"did": did})
+	if err != nil {
+		return nil, err
+	}
 
-			if parentDID, ok := record.Values[1].(string); ok {
-				lineage.ParentDID = &parentDID
-			}
+	if result.Next(ctx) {
+		record := result.Record()
 
-			if spawnedAt, ok := record.Values[4].(neo4j.LocalDateTime); ok {
-				lineage.SpawnedAt = spawnedAt.Time()
-			}
+		lineage := &domain.AgentLineage{
+			AgentDID: record.Values[0].(string),
+			RootDID:  record.Values[2].(string),
+			Depth:    int(record.Values[3].(int64)),
+		}
+
+		if parentDID, ok := record.Values[1].(string); ok {
+			lineage.ParentDID = &parentDID
+		}
 
-			if ancestors, ok := record.Values[5].([]any); ok {
-				for _, a := range ancestors {
-					if s, ok := a.(string); ok {
-						lineage.Ancestors = append(lineage.Ancestors, s)
-					}
+		if spawnedAt, ok := record.Values[4].(neo4j.LocalDateTime); ok {
+			lineage.SpawnedAt = spawnedAt.Time()
+		}
+
+		if ancestors, ok := record.Values[5].([]any); ok {
+			for _, a := range ancestors {
+				if s, ok := a.(string); ok {
+					lineage.Ancestors = append(lineage.Ancestors, s)
 				}
 			}
-
-			return lineage, nil
 		}
 
-		return nil, nil
+		return lineage, nil
+	}
+
+	return nil, nil
+}
+
+// GetSpawnedAgents retrieves all agents spawned by an identity
+func (r *Neo4jRepository) GetSpawnedAgents(ctx context.Context, did string) ([]domain.UnifiedID, error) {
+	ctx = withDID(ctx, did)
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txGetSpawnedAgents(ctx, tx, did)
+		})
 	})
 
 	if err != nil {
 		return nil, err
 	}
-	if result == nil {
-		return nil, fmt.Errorf("lineage not found for: %s", did)
-	}
-	return result.(*domain.AgentLineage), nil
+	return result.([]domain.UnifiedID), nil
 }
 
-// GetSpawnedAgents retrieves all agents spawned by an identity
-func (r *Neo4jRepository) GetSpawnedAgents(ctx context.Context, did string) ([]domain.UnifiedID, error) {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeRead})
-	defer session.Close(ctx)
-
-	result, err := session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
-		query := `
-			MATCH (parent:Identity {did: $did})-[:SPAWNED]->(child:Identity)
-			RETURN child.did as did,
-				   child.entity_type as entity_type,
-				   child.created_at as created_at,
-				   child.public_key as public_key
-		`
-		result, err := tx.Run(ctx, query, map[string]any{"did": did})
-		if err != nil {
-			return nil, err
+func txGetSpawnedAgents(ctx context.Context, tx neo4j.ManagedTransaction, did string) ([]domain.UnifiedID, error) {
+	query := `
+		MATCH (parent:Identity {did: $did})-[:SPAWNED]->(child:Identity)
+		RETURN child.did as did,
+			   child.entity_type as entity_type,
+			   child.created_at as created_at,
+			   child.public_key as public_key,
+			   child.key_type as key_type
+	`
+	result, err := tx.Run(ctx, query, map[string]any{"did": did})
+	if err != nil {
+		return nil, err
+	}
+
+	var agents []domain.UnifiedID
+	for result.Next(ctx) {
+		record := result.Record()
+		agent := domain.UnifiedID{
+			DID:       record.Values[0].(string),
+			ParentDID: &did,
 		}
 
-		var agents []domain.UnifiedID
-		for result.Next(ctx) {
-			record := result.Record()
-			agent := domain.UnifiedID{
-				DID:       record.Values[0].(string),
-				ParentDID: &did,
-			}
+		switch record.Values[1].(string) {
+		case "human":
+			agent.EntityType = domain.EntityTypeHuman
+		case "agent":
+			agent.EntityType = domain.EntityTypeAgent
+		case "organization":
+			agent.EntityType = domain.EntityTypeOrganization
+		}
 
-			switch record.Values[1].(string) {
-			case "human":
-				agent.EntityType = domain.EntityTypeHuman
-			case "agent":
-				agent.EntityType = domain.EntityTypeAgent
-			case "organization":
-				agent.EntityType = domain.EntityTypeOrganization
-			}
+		if createdAt, ok := record.Values[2].(neo4j.LocalDateTime); ok {
+			agent.CreatedAt = createdAt.Time()
+		}
+		if pk, ok := record.Values[3].([]byte); ok {
+			agent.PublicKey = pk
+		}
+		if keyType, ok := record.Values[4].(string); ok {
+			agent.KeyType = parseKeyType(keyType)
+		}
 
-			if createdAt, ok := record.Values[2].(neo4j.LocalDateTime); ok {
-				agent.CreatedAt = createdAt.Time()
-			}
-			if pk, ok := record.Values[3].([]byte); ok {
-				agent.PublicKey = pk
-			}
+		agents = append(agents, agent)
+	}
 
-			agents = append(agents, agent)
-		}
+	return agents, nil
+}
 
-		return agents, nil
+// GetDescendants retrieves the DIDs of rootDID itself and every identity it
+// has spawned, directly or transitively.
+func (r *Neo4jRepository) GetDescendants(ctx context.Context, rootDID string) ([]string, error) {
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txGetDescendants(ctx, tx, rootDID)
+		})
 	})
 
 	if err != nil {
 		return nil, err
 	}
-	return result.([]domain.UnifiedID), nil
+	return result.([]string), nil
+}
+
+func txGetDescendants(ctx context.Context, tx neo4j.ManagedTransaction, rootDID string) ([]string, error) {
+	query := `
+		MATCH (root:Identity {did: $root_did})-[:SPAWNED*0..]->(descendant:Identity)
+		RETURN DISTINCT descendant.did as did
+	`
+	result, err := tx.Run(ctx, query, map[string]any{"root_did": rootDID})
+	if err != nil {
+		return nil, err
+	}
+
+	var dids []string
+	for result.Next(ctx) {
+		if did, ok := result.Record().Values[0].(string); ok {
+			dids = append(dids, did)
+		}
+	}
+
+	return dids, nil
 }
 
 // CreateConstraintsAndIndexes creates necessary Neo4j constraints and indexes
 func (r *Neo4jRepository) CreateConstraintsAndIndexes(ctx context.Context) error {
-	session := r.driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
-	defer session.Close(ctx)
-
 	constraints := []string{
 		"CREATE CONSTRAINT identity_did IF NOT EXISTS FOR (i:Identity) REQUIRE i.did IS UNIQUE",
 	}
@@ -634,15 +1820,17 @@ func (r *Neo4jRepository) CreateConstraintsAndIndexes(ctx context.Context) error
 	}
 
 	for _, constraint := range constraints {
-		_, err := session.Run(ctx, constraint, nil)
-		if err != nil {
+		if _, err := r.withSession(ctx, neo4j.AccessModeWrite, func(session neo4j.SessionWithContext) (any, error) {
+			return session.Run(ctx, constraint, nil)
+		}); err != nil {
 			return fmt.Errorf("failed to create constraint: %w", err)
 		}
 	}
 
 	for _, index := range indexes {
-		_, err := session.Run(ctx, index, nil)
-		if err != nil {
+		if _, err := r.withSession(ctx, neo4j.AccessModeWrite, func(session neo4j.SessionWithContext) (any, error) {
+			return session.Run(ctx, index, nil)
+		}); err != nil {
 			return fmt.Errorf("failed to create index: %w", err)
 		}
 	}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/actoris/actoris/services/identity-cloud/internal/repository")
+
+// didContextKey tags a context with the DID a repository call is acting on,
+// so the span withSession starts can carry it as an attribute without every
+// call site needing to pass a span around by hand.
+type didContextKey struct{}
+
+// withDID attaches did to ctx for the next withSession span to pick up. Call
+// it at the top of any DID-scoped repository method, before the withSession
+// call it wraps.
+func withDID(ctx context.Context, did string) context.Context {
+	return context.WithValue(ctx, didContextKey{}, did)
+}
+
+// startSessionSpan starts a child span for one withSession call, named after
+// the Neo4j access mode it uses, tagged with the DID attached via withDID
+// (if any) so traces line up Neo4j calls with the identity they acted on.
+func startSessionSpan(ctx context.Context, mode neo4j.AccessMode) (context.Context, trace.Span) {
+	name := "Neo4jRepository.read"
+	if mode == neo4j.AccessModeWrite {
+		name = "Neo4jRepository.write"
+	}
+
+	ctx, span := tracer.Start(ctx, name)
+	if did, ok := ctx.Value(didContextKey{}).(string); ok && did != "" {
+		span.SetAttributes(attribute.String("identity.did", did))
+	}
+	return ctx, span
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/actoris/actoris/services/identity-cloud/internal/domain"
+	"github.com/google/uuid"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// CreateWebhookSubscription persists sub as a new (:Webhook) node.
+func (r *Neo4jRepository) CreateWebhookSubscription(ctx context.Context, sub *domain.WebhookSubscription) error {
+	_, err := r.withSession(ctx, neo4j.AccessModeWrite, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return nil, txCreateWebhookSubscription(ctx, tx, sub)
+		})
+	})
+	return err
+}
+
+func txCreateWebhookSubscription(ctx context.Context, tx neo4j.ManagedTransaction, sub *domain.WebhookSubscription) error {
+	query := `
+		CREATE (w:Webhook {
+			id: $id,
+			url: $url,
+			secret: $secret,
+			event_types: $event_types,
+			did_filter: $did_filter,
+			created_at: datetime($created_at)
+		})
+	`
+	eventTypes := make([]string, len(sub.EventTypes))
+	for i, t := range sub.EventTypes {
+		eventTypes[i] = string(t)
+	}
+	var didFilter any
+	if sub.DIDFilter != nil {
+		didFilter = *sub.DIDFilter
+	}
+	params := map[string]any{
+		"id":          sub.ID.String(),
+		"url":         sub.URL,
+		"secret":      sub.Secret,
+		"event_types": eventTypes,
+		"did_filter":  didFilter,
+		"created_at":  sub.CreatedAt.Format(time.RFC3339),
+	}
+	_, err := tx.Run(ctx, query, params)
+	return err
+}
+
+// DeleteWebhookSubscription removes a subscription by id.
+func (r *Neo4jRepository) DeleteWebhookSubscription(ctx context.Context, id uuid.UUID) error {
+	_, err := r.withSession(ctx, neo4j.AccessModeWrite, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteWrite(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			query := `MATCH (w:Webhook {id: $id}) DELETE w`
+			_, err := tx.Run(ctx, query, map[string]any{"id": id.String()})
+			return nil, err
+		})
+	})
+	return err
+}
+
+// ListWebhookSubscriptions returns every registered subscription, for the
+// dispatcher to match incoming events against.
+func (r *Neo4jRepository) ListWebhookSubscriptions(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	result, err := r.withSession(ctx, neo4j.AccessModeRead, func(session neo4j.SessionWithContext) (any, error) {
+		return session.ExecuteRead(ctx, func(tx neo4j.ManagedTransaction) (any, error) {
+			return txListWebhookSubscriptions(ctx, tx)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.([]domain.WebhookSubscription), nil
+}
+
+func txListWebhookSubscriptions(ctx context.Context, tx neo4j.ManagedTransaction) ([]domain.WebhookSubscription, error) {
+	query := `
+		MATCH (w:Webhook)
+		RETURN w.id as id,
+			   w.url as url,
+			   w.secret as secret,
+			   w.event_types as event_types,
+			   w.did_filter as did_filter,
+			   w.created_at as created_at
+	`
+	result, err := tx.Run(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []domain.WebhookSubscription
+	for result.Next(ctx) {
+		record := result.Record()
+
+		id, err := uuid.Parse(record.Values[0].(string))
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook id: %w", err)
+		}
+
+		rawTypes, _ := record.Values[3].([]any)
+		eventTypes := make([]domain.WebhookEventType, 0, len(rawTypes))
+		for _, rt := range rawTypes {
+			if s, ok := rt.(string); ok {
+				eventTypes = append(eventTypes, domain.WebhookEventType(s))
+			}
+		}
+
+		sub := domain.WebhookSubscription{
+			ID:         id,
+			URL:        record.Values[1].(string),
+			Secret:     record.Values[2].(string),
+			EventTypes: eventTypes,
+		}
+		if didFilter, ok := record.Values[4].(string); ok {
+			sub.DIDFilter = &didFilter
+		}
+		if createdAt, ok := record.Values[5].(neo4j.LocalDateTime); ok {
+			sub.CreatedAt = createdAt.Time()
+		}
+
+		subs = append(subs, sub)
+	}
+	return subs, result.Err()
+}
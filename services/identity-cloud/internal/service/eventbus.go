@@ -0,0 +1,147 @@
+package service
+
+import "sync"
+
+// EventTopic identifies what kind of change an Event represents.
+type EventTopic int
+
+const (
+	TopicTrustScore EventTopic = iota
+	TopicWallet
+)
+
+// Event is one change notification for a DID's trust score or wallet.
+// Version mirrors the domain object's own optimistic-concurrency version (so
+// a client can resume with since_version); Cursor is this event's position
+// within its topic's ring buffer, used only to order replayed events
+// relative to each other. Data is a *domain.ClaimableTrustDelta for
+// TopicTrustScore or a *domain.HCWallet for TopicWallet.
+type Event struct {
+	DID     string
+	Topic   EventTopic
+	Version uint64
+	Cursor  uint64
+	Data    any
+}
+
+// ringBufferCapacity bounds how many events are retained per DID+topic for
+// reconnecting subscribers to replay.
+const ringBufferCapacity = 64
+
+// eventRing is a fixed-capacity backlog of recent events for one DID+topic.
+// It has no lock of its own: callers must hold the owning topicState's mu,
+// so that Subscribe's backlog snapshot + subscriber registration can happen
+// under the same critical section as Publish's push + fan-out (see
+// topicState).
+type eventRing struct {
+	events     []Event
+	nextCursor uint64
+}
+
+func (r *eventRing) push(e Event) Event {
+	r.nextCursor++
+	e.Cursor = r.nextCursor
+	r.events = append(r.events, e)
+	if len(r.events) > ringBufferCapacity {
+		r.events = r.events[len(r.events)-ringBufferCapacity:]
+	}
+	return e
+}
+
+// since returns buffered events newer than sinceVersion, in publish order.
+func (r *eventRing) since(sinceVersion uint64) []Event {
+	out := make([]Event, 0, len(r.events))
+	for _, e := range r.events {
+		if e.Version > sinceVersion {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// topicState is the ring buffer and live subscriber set for one DID+topic.
+// Both are guarded by the same mu: Publish pushes onto ring and fans out to
+// subs under one critical section, and Subscribe snapshots ring.since and
+// registers into subs under that same critical section, so a Publish can
+// never land in the gap between a new subscriber's backlog read and its
+// registration and be silently missed by that subscriber.
+type topicState struct {
+	mu   sync.Mutex
+	ring eventRing
+	subs map[chan Event]struct{}
+}
+
+// EventBus fans out trust score and wallet changes to live watchers, and
+// keeps a short replay buffer per DID+topic so a client that reconnects with
+// since_version doesn't miss events that happened while it was disconnected.
+type EventBus struct {
+	mu     sync.Mutex
+	topics map[string]*topicState
+}
+
+// NewEventBus creates an empty EventBus.
+func NewEventBus() *EventBus {
+	return &EventBus{topics: make(map[string]*topicState)}
+}
+
+func topicKey(did string, topic EventTopic) string {
+	if topic == TopicWallet {
+		return did + ":wallet"
+	}
+	return did + ":trust_score"
+}
+
+func (b *EventBus) stateFor(did string, topic EventTopic) *topicState {
+	key := topicKey(did, topic)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ts, ok := b.topics[key]
+	if !ok {
+		ts = &topicState{subs: make(map[chan Event]struct{})}
+		b.topics[key] = ts
+	}
+	return ts
+}
+
+// Publish records an event in its topic's ring buffer and delivers it to
+// every live subscriber. A subscriber that isn't keeping up has the event
+// dropped on its channel rather than blocking the publisher; it picks the
+// gap back up from the ring buffer the next time it resumes with
+// since_version.
+func (b *EventBus) Publish(did string, topic EventTopic, version uint64, data any) {
+	ts := b.stateFor(did, topic)
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	e := ts.ring.push(Event{DID: did, Topic: topic, Version: version, Data: data})
+	for ch := range ts.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe registers a new watcher for did's topic. It returns a channel of
+// live events, an unsubscribe function the caller must call exactly once
+// when done, and the backlog of buffered events newer than sinceVersion so
+// the caller can replay what it missed before switching over to the live
+// channel.
+func (b *EventBus) Subscribe(did string, topic EventTopic, sinceVersion uint64) (<-chan Event, func(), []Event) {
+	ts := b.stateFor(did, topic)
+
+	ch := make(chan Event, 16)
+	ts.mu.Lock()
+	backlog := ts.ring.since(sinceVersion)
+	ts.subs[ch] = struct{}{}
+	ts.mu.Unlock()
+
+	unsubscribe := func() {
+		ts.mu.Lock()
+		delete(ts.subs, ch)
+		ts.mu.Unlock()
+	}
+
+	return ch, unsubscribe, backlog
+}
@@ -3,49 +3,127 @@ package service
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"crypto/ed25519"
-	"encoding/base64"
+	"crypto/elliptic"
+	"crypto/sha256"
 	"fmt"
+	"math/big"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/actoris/actoris/services/identity-cloud/internal/didkey"
 	"github.com/actoris/actoris/services/identity-cloud/internal/domain"
+	"github.com/actoris/actoris/services/identity-cloud/internal/httpguard"
+	"github.com/actoris/actoris/services/identity-cloud/internal/keys"
 	"github.com/actoris/actoris/services/identity-cloud/internal/repository"
+	"github.com/actoris/actoris/services/identity-cloud/internal/resolver"
+	"github.com/actoris/actoris/services/identity-cloud/internal/vc"
+	"github.com/actoris/actoris/services/identity-cloud/internal/webhooks"
+	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
 // IdentityService provides business logic for identity management
 type IdentityService struct {
-	repo *repository.Neo4jRepository
+	repo                 *repository.Neo4jRepository
+	governanceDIDs       map[string]bool
+	events               *EventBus
+	minCohortSize        int
+	masterSeed           []byte
+	trustScoreThresholds []uint32
+	webhooks             *webhooks.Dispatcher
+	resolver             *resolver.Resolver
 }
 
-// NewIdentityService creates a new identity service
-func NewIdentityService(repo *repository.Neo4jRepository) *IdentityService {
-	return &IdentityService{repo: repo}
+// NewIdentityService creates a new identity service. governanceDIDs are the
+// DIDs authorized to propose and vote on wallet halts; see ProposeHalt.
+// minCohortSize is the minimum sibling cohort size lineage trust inheritance
+// requires before trimming outliers; see AggregateInheritedTau. masterSeed is
+// the root secret every identity's keypair is derived from; see
+// internal/keys and SignAs. trustScoreThresholds are the score values that
+// trigger a trust_score.threshold_crossed webhook when a ClaimTrustDelta call
+// moves a DID's score from one side of a threshold to the other; the claim
+// repository.GetTrustScore folds into a read does not trigger it.
+// dispatcher may be nil, in
+// which case webhook emission is a no-op. didResolver resolves did:web
+// identifiers for ResolveDID and VerifyDIDSignature; see internal/resolver.
+func NewIdentityService(repo *repository.Neo4jRepository, governanceDIDs []string, minCohortSize int, masterSeed []byte, trustScoreThresholds []uint32, dispatcher *webhooks.Dispatcher, didResolver *resolver.Resolver) *IdentityService {
+	govDIDs := make(map[string]bool, len(governanceDIDs))
+	for _, did := range governanceDIDs {
+		govDIDs[did] = true
+	}
+	return &IdentityService{
+		repo:                 repo,
+		governanceDIDs:       govDIDs,
+		events:               NewEventBus(),
+		minCohortSize:        minCohortSize,
+		masterSeed:           masterSeed,
+		trustScoreThresholds: trustScoreThresholds,
+		webhooks:             dispatcher,
+		resolver:             didResolver,
+	}
 }
 
-// CreateIdentity creates a new identity with DID generation
+// emitWebhook records a webhook event for did if a dispatcher is configured.
+// It is best-effort, matching publishTrustScoreEvent/publishWalletEvent: a
+// delivery failure never unwinds the mutation that already committed.
+func (s *IdentityService) emitWebhook(ctx context.Context, eventType domain.WebhookEventType, did string, data any) {
+	if s.webhooks == nil {
+		return
+	}
+	s.webhooks.Emit(ctx, domain.WebhookEvent{
+		ID:         uuid.New(),
+		Type:       eventType,
+		DID:        did,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	})
+}
+
+// CreateIdentity creates a new identity, deriving its Ed25519 keypair from
+// the master seed rather than generating (and discarding) a random one, so
+// the private key can always be re-derived on demand by SignAs. Only the
+// derivation path - parentDID plus a monotonic counter scoped to it - is
+// persisted; see internal/keys.
 func (s *IdentityService) CreateIdentity(ctx context.Context, entityType domain.EntityType, parentDID *string) (*domain.UnifiedID, error) {
-	// Generate Ed25519 keypair
-	publicKey, _, err := ed25519.GenerateKey(nil)
+	parentScope := ""
+	if parentDID != nil {
+		parentScope = *parentDID
+	}
+	index, err := s.repo.AllocateDerivationIndex(ctx, parentScope)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate keypair: %w", err)
+		return nil, fmt.Errorf("failed to allocate derivation index: %w", err)
+	}
+
+	path := keys.DerivationPath{ParentDID: parentScope, EntityType: entityType, Index: index}
+	publicKey, _, err := keys.Derive(s.masterSeed, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive keypair: %w", err)
 	}
 
 	// Generate did:key from public key
-	did := generateDIDKey(publicKey)
+	did, err := didkey.Encode(domain.KeyTypeEd25519, publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode did:key: %w", err)
+	}
 
 	identity := &domain.UnifiedID{
-		DID:        did,
-		EntityType: entityType,
-		ParentDID:  parentDID,
-		CreatedAt:  time.Now().UTC(),
-		PublicKey:  publicKey,
+		DID:             did,
+		EntityType:      entityType,
+		ParentDID:       parentDID,
+		CreatedAt:       time.Now().UTC(),
+		PublicKey:       publicKey,
+		KeyType:         domain.KeyTypeEd25519,
+		DerivationIndex: index,
 	}
 
 	// Create in database
 	if err := s.repo.CreateIdentity(ctx, identity); err != nil {
 		return nil, fmt.Errorf("failed to create identity: %w", err)
 	}
+	s.emitWebhook(ctx, domain.WebhookEventIdentityCreated, did, identity)
 
 	// If spawned from parent, inherit trust score
 	if parentDID != nil {
@@ -53,24 +131,126 @@ func (s *IdentityService) CreateIdentity(ctx context.Context, entityType domain.
 			// Log but don't fail - identity was created
 			fmt.Printf("Warning: failed to inherit parent trust: %v\n", err)
 		}
+		s.emitWebhook(ctx, domain.WebhookEventIdentitySpawned, did, identity)
 	}
 
 	return identity, nil
 }
 
+// DerivePrivateKey re-derives did's Ed25519 private key from the master seed
+// and its stored derivation path. The private key is never persisted; this
+// is the only way to obtain it.
+func (s *IdentityService) DerivePrivateKey(ctx context.Context, did string) (ed25519.PrivateKey, error) {
+	identity, err := s.repo.GetIdentity(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	if identity.KeyType != domain.KeyTypeEd25519 {
+		return nil, fmt.Errorf("derivation is only supported for Ed25519 identities, got %s", identity.KeyType)
+	}
+
+	parentScope := ""
+	if identity.ParentDID != nil {
+		parentScope = *identity.ParentDID
+	}
+	path := keys.DerivationPath{ParentDID: parentScope, EntityType: identity.EntityType, Index: identity.DerivationIndex}
+
+	publicKey, privateKey, err := keys.Derive(s.masterSeed, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive keypair: %w", err)
+	}
+	if !publicKey.Equal(ed25519.PublicKey(identity.PublicKey)) {
+		return nil, fmt.Errorf("derived public key does not match stored public key for %s", did)
+	}
+	return privateKey, nil
+}
+
+// SignAs re-derives did's private key and signs msg with it.
+func (s *IdentityService) SignAs(ctx context.Context, did string, msg []byte) ([]byte, error) {
+	privateKey, err := s.DerivePrivateKey(ctx, did)
+	if err != nil {
+		return nil, err
+	}
+	return ed25519.Sign(privateKey, msg), nil
+}
+
 // GetIdentity retrieves an identity by DID
 func (s *IdentityService) GetIdentity(ctx context.Context, did string) (*domain.UnifiedID, error) {
 	return s.repo.GetIdentity(ctx, did)
 }
 
-// GetTrustScore retrieves the trust score for an identity
+// GetTrustScore retrieves the trust score for an identity. The claim of any
+// verification outcomes accrued since the last claim happens inside
+// s.repo.GetTrustScore itself, so every internal consumer of a trust score
+// -- not just this path -- sees claimed, up-to-date components.
 func (s *IdentityService) GetTrustScore(ctx context.Context, did string) (*domain.TrustScore, error) {
 	return s.repo.GetTrustScore(ctx, did)
 }
 
-// RecordVerificationOutcome updates trust based on verification result
+// RecordVerificationOutcome appends a verification outcome for later
+// claiming. See GetClaimableTrustDelta and ClaimTrustDelta for how outcomes
+// are folded into the trust score. The append itself does not touch the
+// TrustScore node, but publishTrustScoreEvent reads the score through
+// repository.GetTrustScore, which claims pending outcomes first, so the
+// published event carries the newly claimed TrustScore version along with
+// its ClaimableTrustDelta projection.
 func (s *IdentityService) RecordVerificationOutcome(ctx context.Context, did string, passed bool, latencyMs uint32) error {
-	return s.repo.RecordVerificationOutcome(ctx, did, passed, latencyMs)
+	if err := s.repo.RecordVerificationOutcome(ctx, did, passed, latencyMs); err != nil {
+		return err
+	}
+	s.publishTrustScoreEvent(ctx, did)
+	s.emitWebhook(ctx, domain.WebhookEventVerificationOutcomeRecorded, did, map[string]any{"passed": passed, "latency_ms": latencyMs})
+	return nil
+}
+
+// GetClaimableTrustDelta returns the trust score state that would result
+// from claiming every unclaimed verification outcome for did right now.
+func (s *IdentityService) GetClaimableTrustDelta(ctx context.Context, did string) (*domain.ClaimableTrustDelta, error) {
+	return s.repo.GetClaimableTrustDelta(ctx, did)
+}
+
+// ClaimTrustDelta folds accrued verification outcomes into the trust score,
+// gated on expectedVersion for optimistic concurrency.
+func (s *IdentityService) ClaimTrustDelta(ctx context.Context, did string, expectedVersion uint64) error {
+	// before is read raw, without GetTrustScore's claim-on-read, so the
+	// expectedVersion gate and the threshold check both see the pre-claim score.
+	tx, err := s.repo.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	before, err := tx.GetTrustScore(ctx, did)
+	tx.Rollback(ctx)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return fmt.Errorf("trust score not found for: %s", did)
+	}
+	if err := s.repo.ClaimTrustDelta(ctx, did, expectedVersion); err != nil {
+		return err
+	}
+	s.publishTrustScoreEvent(ctx, did)
+	s.emitThresholdCrossings(ctx, did, before)
+	return nil
+}
+
+// emitThresholdCrossings re-reads did's trust score and fires a
+// trust_score.threshold_crossed webhook for each configured threshold that
+// before's score was on one side of and the refreshed score is now on the
+// other, in either direction.
+func (s *IdentityService) emitThresholdCrossings(ctx context.Context, did string, before *domain.TrustScore) {
+	if s.webhooks == nil || len(s.trustScoreThresholds) == 0 {
+		return
+	}
+	after, err := s.repo.GetTrustScore(ctx, did)
+	if err != nil {
+		return
+	}
+	for _, threshold := range s.trustScoreThresholds {
+		if (before.Score < threshold) != (after.Score < threshold) {
+			s.emitWebhook(ctx, domain.WebhookEventTrustScoreThresholdCrossed, did, after)
+		}
+	}
 }
 
 // GetHCWallet retrieves the HC wallet for an identity
@@ -78,14 +258,20 @@ func (s *IdentityService) GetHCWallet(ctx context.Context, did string) (*domain.
 	return s.repo.GetHCWallet(ctx, did)
 }
 
-// LockHCForEscrow locks HC for an escrow transaction
-func (s *IdentityService) LockHCForEscrow(ctx context.Context, did string, amount decimal.Decimal) error {
+// LockHCForEscrow locks HC for an escrow transaction. idempotencyKey must be
+// a caller-supplied UUID unique to this logical request; replaying the same
+// key after a retry is a safe no-op instead of double-locking funds.
+func (s *IdentityService) LockHCForEscrow(ctx context.Context, did string, amount decimal.Decimal, idempotencyKey string) error {
 	wallet, err := s.repo.GetHCWallet(ctx, did)
 	if err != nil {
 		return err
 	}
 
 	if wallet.IsExpired() {
+		// This is the one place the service actually observes a wallet's
+		// expiry, rather than just computing it on demand, so it's the
+		// natural point to fire wallet.expired for anyone watching.
+		s.emitWebhook(ctx, domain.WebhookEventWalletExpired, did, wallet)
 		return fmt.Errorf("wallet has expired credits")
 	}
 
@@ -93,22 +279,71 @@ func (s *IdentityService) LockHCForEscrow(ctx context.Context, did string, amoun
 		return fmt.Errorf("insufficient balance: available=%s, requested=%s", wallet.Available, amount)
 	}
 
-	return s.repo.LockHC(ctx, did, amount, wallet.Version)
+	if err := s.repo.LockHC(ctx, did, amount, wallet.Version, idempotencyKey); err != nil {
+		return err
+	}
+	s.publishWalletEvent(ctx, did)
+	s.emitWalletWebhook(ctx, domain.WebhookEventWalletLocked, did)
+	return nil
 }
 
-// ReleaseHCFromEscrow releases HC after successful transaction
-func (s *IdentityService) ReleaseHCFromEscrow(ctx context.Context, did string, amount decimal.Decimal) error {
-	return s.repo.ReleaseHC(ctx, did, amount)
+// ReleaseHCFromEscrow releases HC after successful transaction. See
+// LockHCForEscrow for the idempotency-key semantics.
+func (s *IdentityService) ReleaseHCFromEscrow(ctx context.Context, did string, amount decimal.Decimal, idempotencyKey string) error {
+	if err := s.repo.ReleaseHC(ctx, did, amount, idempotencyKey); err != nil {
+		return err
+	}
+	s.publishWalletEvent(ctx, did)
+	s.emitWalletWebhook(ctx, domain.WebhookEventWalletReleased, did)
+	return nil
 }
 
-// RefundHCFromEscrow refunds HC back to available balance
-func (s *IdentityService) RefundHCFromEscrow(ctx context.Context, did string, amount decimal.Decimal) error {
-	return s.repo.RefundHC(ctx, did, amount)
+// RefundHCFromEscrow refunds HC back to available balance. See
+// LockHCForEscrow for the idempotency-key semantics.
+func (s *IdentityService) RefundHCFromEscrow(ctx context.Context, did string, amount decimal.Decimal, idempotencyKey string) error {
+	if err := s.repo.RefundHC(ctx, did, amount, idempotencyKey); err != nil {
+		return err
+	}
+	s.publishWalletEvent(ctx, did)
+	s.emitWalletWebhook(ctx, domain.WebhookEventWalletRefunded, did)
+	return nil
 }
 
-// CreditHC adds HC to a wallet
-func (s *IdentityService) CreditHC(ctx context.Context, did string, amount decimal.Decimal) error {
-	return s.repo.CreditHC(ctx, did, amount)
+// CreditHC adds HC to a wallet. See LockHCForEscrow for the idempotency-key
+// semantics.
+func (s *IdentityService) CreditHC(ctx context.Context, did string, amount decimal.Decimal, idempotencyKey string) error {
+	if err := s.repo.CreditHC(ctx, did, amount, idempotencyKey); err != nil {
+		return err
+	}
+	s.publishWalletEvent(ctx, did)
+	s.emitWalletWebhook(ctx, domain.WebhookEventWalletCredited, did)
+	return nil
+}
+
+// emitWalletWebhook re-reads did's wallet and emits eventType with its
+// current state. See publishWalletEvent for why a lookup failure here is
+// swallowed rather than surfaced.
+func (s *IdentityService) emitWalletWebhook(ctx context.Context, eventType domain.WebhookEventType, did string) {
+	if s.webhooks == nil {
+		return
+	}
+	wallet, err := s.repo.GetHCWallet(ctx, did)
+	if err != nil {
+		return
+	}
+	s.emitWebhook(ctx, eventType, did, wallet)
+}
+
+// GetWalletHistory retrieves up to pageSize ledger entries for a wallet
+// newer than cursor, plus the cursor to resume from on the next call. See
+// Neo4jRepository.GetWalletHistory for the pageSize bound and clamping.
+func (s *IdentityService) GetWalletHistory(ctx context.Context, did string, cursor uint64, pageSize int) ([]domain.Transfer, uint64, error) {
+	return s.repo.GetWalletHistory(ctx, did, cursor, pageSize)
+}
+
+// GetBalanceAt reconstructs a wallet's total balance as of a point in time
+func (s *IdentityService) GetBalanceAt(ctx context.Context, did string, at time.Time) (decimal.Decimal, error) {
+	return s.repo.GetBalanceAt(ctx, did, at)
 }
 
 // GetAgentLineage retrieves the full lineage of an agent
@@ -116,11 +351,83 @@ func (s *IdentityService) GetAgentLineage(ctx context.Context, did string) (*dom
 	return s.repo.GetAgentLineage(ctx, did)
 }
 
+// ResolveInheritedTau computes the Byzantine-fault-tolerant inherited tau a
+// child spawned under parentDID would receive, aggregating trimmed-mean
+// sibling cohorts across parentDID's full ancestor chain. The returned
+// InheritanceExplanation exposes the per-generation weights and trimmed
+// values for auditability.
+func (s *IdentityService) ResolveInheritedTau(ctx context.Context, parentDID string) (float64, domain.InheritanceExplanation, error) {
+	cohorts, parentTau, err := s.buildAncestorCohorts(ctx, parentDID)
+	if err != nil {
+		return 0, domain.InheritanceExplanation{}, err
+	}
+	tau, explanation := domain.AggregateInheritedTau(cohorts, parentTau, s.minCohortSize)
+	return tau, explanation, nil
+}
+
+// buildAncestorCohorts walks parentDID's ancestor chain (from parentDID
+// itself out to the root) and, for each generation, fetches that ancestor's
+// own tau plus the tau of every sibling sharing its parent. Depth 1 is
+// parentDID's own generation, depth 2 its parent, and so on.
+func (s *IdentityService) buildAncestorCohorts(ctx context.Context, parentDID string) ([]domain.GenerationCohort, float64, error) {
+	lineage, err := s.repo.GetAgentLineage(ctx, parentDID)
+	if err != nil {
+		return nil, 0, err
+	}
+	chain := lineage.Ancestors // root...parentDID, parentDID last
+
+	cohorts := make([]domain.GenerationCohort, 0, len(chain))
+	var parentTau float64
+	for i := len(chain) - 1; i >= 0; i-- {
+		ancestorDID := chain[i]
+		depth := len(chain) - i
+
+		ancestorTrust, err := s.repo.GetTrustScore(ctx, ancestorDID)
+		if err != nil {
+			return nil, 0, err
+		}
+		ancestorTau := ancestorTrust.Tau()
+
+		var siblingTaus []float64
+		if i > 0 {
+			siblingTaus, err = s.repo.GetSiblingCohortTaus(ctx, chain[i-1])
+			if err != nil {
+				return nil, 0, err
+			}
+		} else {
+			siblingTaus = []float64{ancestorTau}
+		}
+
+		cohorts = append(cohorts, domain.GenerationCohort{
+			Depth:       depth,
+			AncestorTau: ancestorTau,
+			SiblingTaus: siblingTaus,
+		})
+		if depth == 1 {
+			parentTau = ancestorTau
+		}
+	}
+
+	return cohorts, parentTau, nil
+}
+
 // GetSpawnedAgents retrieves all agents spawned by an identity
 func (s *IdentityService) GetSpawnedAgents(ctx context.Context, did string) ([]domain.UnifiedID, error) {
 	return s.repo.GetSpawnedAgents(ctx, did)
 }
 
+// ListSpawnedAgents retrieves a page of agents spawned by did, narrowed by
+// filterExpr (a filter-package expression over entity_type, created_at,
+// trust.score, trust.components.*, wallet.available, wallet.expires_at, and
+// parent_did; empty means no filter) and paginated via pageToken/pageSize.
+// It returns the page and the token for the next page, empty once
+// exhausted. Unlike GetSpawnedAgents, filtering and pagination happen
+// inside Neo4j, so this is the one to use for cohorts too large to return
+// in full.
+func (s *IdentityService) ListSpawnedAgents(ctx context.Context, did, filterExpr, pageToken string, pageSize int) ([]domain.UnifiedID, string, error) {
+	return s.repo.ListSpawnedAgents(ctx, did, filterExpr, pageToken, pageSize)
+}
+
 // CalculateDiscount calculates the trust-based discount for pricing
 func (s *IdentityService) CalculateDiscount(ctx context.Context, did string) (float64, error) {
 	trust, err := s.repo.GetTrustScore(ctx, did)
@@ -130,15 +437,19 @@ func (s *IdentityService) CalculateDiscount(ctx context.Context, did string) (fl
 	return trust.DiscountRate(), nil
 }
 
-// inheritParentTrust inherits trust score from parent identity
+// inheritParentTrust inherits trust score from parent identity, using a
+// lineage-weighted aggregation across ancestor sibling cohorts rather than
+// naive geometric decay off the parent alone; see AggregateInheritedTau.
 func (s *IdentityService) inheritParentTrust(ctx context.Context, childDID, parentDID string) error {
 	parentTrust, err := s.repo.GetTrustScore(ctx, parentDID)
 	if err != nil {
 		return err
 	}
 
-	// Calculate inherited trust (30% of parent)
-	inheritedTau := parentTrust.InheritedTauForChild()
+	inheritedTau, _, err := s.ResolveInheritedTau(ctx, parentDID)
+	if err != nil {
+		return err
+	}
 	inheritedScore := uint32(inheritedTau * float64(domain.MaxScore))
 
 	// Get child's current trust score
@@ -155,26 +466,516 @@ func (s *IdentityService) inheritParentTrust(ctx context.Context, childDID, pare
 	return s.repo.UpdateTrustScore(ctx, childDID, childTrust)
 }
 
-// generateDIDKey generates a did:key from an Ed25519 public key
-func generateDIDKey(publicKey ed25519.PublicKey) string {
-	// Multicodec prefix for Ed25519 public key (0xed01)
-	multicodec := []byte{0xed, 0x01}
-	encoded := append(multicodec, publicKey...)
-
-	// Base58btc encode
-	return "did:key:z" + base64.RawURLEncoding.EncodeToString(encoded)
+// ResolveDIDKey parses a did:key identifier into its key type and raw public
+// key bytes without touching the database, since a did:key is self-certifying.
+func (s *IdentityService) ResolveDIDKey(did string) (domain.KeyType, []byte, error) {
+	return didkey.Decode(did)
 }
 
-// VerifyDIDSignature verifies a signature against a DID's public key
+// VerifyDIDSignature verifies a signature against the public key behind
+// did. did:key identifiers are self-certifying and are decoded directly,
+// with no repository lookup required, so this also verifies signatures
+// from identities that were never registered locally. did:web identifiers
+// are resolved via ResolveDID. Any other DID method falls back to the
+// locally stored identity record.
 func (s *IdentityService) VerifyDIDSignature(ctx context.Context, did string, message, signature []byte) (bool, error) {
-	identity, err := s.repo.GetIdentity(ctx, did)
+	switch {
+	case strings.HasPrefix(did, "did:key:"):
+		keyType, publicKey, err := didkey.Decode(did)
+		if err != nil {
+			return false, err
+		}
+		return verifySignatureForKeyType(keyType, publicKey, message, signature)
+
+	case strings.HasPrefix(did, "did:web:"):
+		methods, err := s.ResolveDID(ctx, did)
+		if err != nil {
+			return false, err
+		}
+		for _, vm := range methods {
+			if valid, err := verifySignatureForKeyType(vm.KeyType, vm.PublicKey, message, signature); err == nil && valid {
+				return true, nil
+			}
+		}
+		return false, nil
+
+	default:
+		identity, err := s.repo.GetIdentity(ctx, did)
+		if err != nil {
+			return false, err
+		}
+		return verifySignatureForKeyType(identity.KeyType, identity.PublicKey, message, signature)
+	}
+}
+
+// ResolveDID returns the normalized verification methods for an externally
+// hosted did:web identity, so callers can verify signatures from identities
+// that were never created through CreateIdentity. It requires a resolver to
+// have been configured; see NewIdentityService.
+func (s *IdentityService) ResolveDID(ctx context.Context, did string) ([]resolver.VerificationMethod, error) {
+	if s.resolver == nil {
+		return nil, fmt.Errorf("no DID resolver configured")
+	}
+	return s.resolver.Resolve(ctx, did)
+}
+
+// verifySignatureForKeyType dispatches signature verification to the
+// algorithm matching keyType.
+func verifySignatureForKeyType(keyType domain.KeyType, publicKey, message, signature []byte) (bool, error) {
+	switch keyType {
+	case domain.KeyTypeEd25519:
+		if len(publicKey) != ed25519.PublicKeySize {
+			return false, fmt.Errorf("invalid public key length")
+		}
+		return ed25519.Verify(publicKey, message, signature), nil
+
+	case domain.KeyTypeP256:
+		curve := elliptic.P256()
+		x, y := elliptic.UnmarshalCompressed(curve, publicKey)
+		if x == nil {
+			return false, fmt.Errorf("invalid P-256 public key")
+		}
+		if len(signature) != 64 {
+			return false, fmt.Errorf("invalid P-256 signature length")
+		}
+		digest := sha256.Sum256(message)
+		r := new(big.Int).SetBytes(signature[:32])
+		sVal := new(big.Int).SetBytes(signature[32:])
+		pub := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		return ecdsa.Verify(pub, digest[:], r, sVal), nil
+
+	case domain.KeyTypeSecp256k1:
+		return false, fmt.Errorf("secp256k1 signature verification is not supported in this build")
+
+	default:
+		return false, fmt.Errorf("unsupported key type: %s", keyType)
+	}
+}
+
+// ProposeHalt proposes a governance-controlled freeze on wallet mutations.
+// proposerDID must be one of the designated governance DIDs; the halt takes
+// effect only once quorum signatures have been collected via VoteHalt.
+func (s *IdentityService) ProposeHalt(ctx context.Context, proposerDID string, scope domain.HaltScope, target string, effectiveAt time.Time, reason string, quorum int) (*domain.HaltEntry, error) {
+	if !s.governanceDIDs[proposerDID] {
+		return nil, fmt.Errorf("%s is not a designated governance DID", proposerDID)
+	}
+	if quorum <= 0 {
+		return nil, fmt.Errorf("quorum must be positive")
+	}
+	if scope != domain.HaltScopeGlobal && target == "" {
+		return nil, fmt.Errorf("target is required for halt scope %s", scope)
+	}
+
+	halt := &domain.HaltEntry{
+		ID:          uuid.NewString(),
+		Scope:       scope,
+		Target:      target,
+		EffectiveAt: effectiveAt,
+		Reason:      reason,
+		ProposedBy:  proposerDID,
+		Quorum:      quorum,
+		Status:      domain.HaltStatusPending,
+	}
+	if err := s.repo.ProposeHalt(ctx, halt); err != nil {
+		return nil, fmt.Errorf("failed to propose halt: %w", err)
+	}
+	return halt, nil
+}
+
+// VoteHalt records signerDID's vote for a halt proposal. signerDID must be a
+// designated governance DID and signature must verify against the halt ID
+// using that DID's registered public key.
+func (s *IdentityService) VoteHalt(ctx context.Context, haltID, signerDID string, signature []byte) (*domain.HaltEntry, error) {
+	if !s.governanceDIDs[signerDID] {
+		return nil, fmt.Errorf("%s is not a designated governance DID", signerDID)
+	}
+
+	valid, err := s.VerifyDIDSignature(ctx, signerDID, []byte(haltID), signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify governance signature: %w", err)
+	}
+	if !valid {
+		return nil, fmt.Errorf("invalid governance signature for halt %s", haltID)
+	}
+
+	return s.repo.VoteHalt(ctx, haltID, signerDID, signature)
+}
+
+// ListHalts returns every proposed halt, or only those currently active
+// when activeOnly is set.
+func (s *IdentityService) ListHalts(ctx context.Context, activeOnly bool) ([]domain.HaltEntry, error) {
+	return s.repo.ListHalts(ctx, activeOnly)
+}
+
+// CancelHalt cancels a halt proposal or active halt. callerDID must be a
+// designated governance DID.
+func (s *IdentityService) CancelHalt(ctx context.Context, callerDID, haltID string) error {
+	if !s.governanceDIDs[callerDID] {
+		return fmt.Errorf("%s is not a designated governance DID", callerDID)
+	}
+	return s.repo.CancelHalt(ctx, haltID)
+}
+
+// CheckWalletHalt returns an error if an active halt currently freezes
+// wallet mutations for did. Call this at the top of every wallet-mutating
+// RPC (CreditWallet, DebitWallet, LockWallet, ReleaseWallet) so a
+// compromised agent lineage can be frozen without redeploying.
+func (s *IdentityService) CheckWalletHalt(ctx context.Context, did string) error {
+	halts, err := s.repo.ListHalts(ctx, true)
+	if err != nil {
+		return fmt.Errorf("failed to check wallet halts: %w", err)
+	}
+	if len(halts) == 0 {
+		return nil
+	}
+
+	var entityType domain.EntityType
+	needsEntityType := false
+	for _, h := range halts {
+		if h.Scope == domain.HaltScopeEntityType {
+			needsEntityType = true
+			break
+		}
+	}
+	if needsEntityType {
+		identity, err := s.repo.GetIdentity(ctx, did)
+		if err != nil {
+			return fmt.Errorf("failed to resolve identity for halt check: %w", err)
+		}
+		entityType = identity.EntityType
+	}
+
+	now := time.Now()
+	for _, h := range halts {
+		if h.Applies(did, entityType, now) {
+			return fmt.Errorf("wallet operations are halted for %s: %s (halt %s)", did, h.Reason, h.ID)
+		}
+	}
+	return nil
+}
+
+// publishTrustScoreEvent re-reads did's trust state and publishes it to the
+// trust score topic. It is best-effort: a failure here only means a watcher
+// misses a live update, not that the mutation that already committed gets
+// rolled back, so the error is swallowed.
+func (s *IdentityService) publishTrustScoreEvent(ctx context.Context, did string) {
+	trust, err := s.repo.GetTrustScore(ctx, did)
+	if err != nil {
+		return
+	}
+	delta, err := s.repo.GetClaimableTrustDelta(ctx, did)
+	if err != nil {
+		return
+	}
+	s.events.Publish(did, TopicTrustScore, trust.Version, delta)
+}
+
+// publishWalletEvent re-reads did's wallet and publishes it to the wallet
+// topic. See publishTrustScoreEvent for why errors are swallowed here.
+func (s *IdentityService) publishWalletEvent(ctx context.Context, did string) {
+	wallet, err := s.repo.GetHCWallet(ctx, did)
+	if err != nil {
+		return
+	}
+	s.events.Publish(did, TopicWallet, wallet.Version, wallet)
+}
+
+// WatchTrustScore subscribes to trust score changes for did. It returns a
+// channel of live events, an unsubscribe function the caller must call
+// exactly once when done watching, and the backlog of buffered events newer
+// than sinceVersion.
+func (s *IdentityService) WatchTrustScore(did string, sinceVersion uint64) (<-chan Event, func(), []Event) {
+	return s.events.Subscribe(did, TopicTrustScore, sinceVersion)
+}
+
+// WatchWallet subscribes to wallet changes for did. See WatchTrustScore for
+// the return values.
+func (s *IdentityService) WatchWallet(did string, sinceVersion uint64) (<-chan Event, func(), []Event) {
+	return s.events.Subscribe(did, TopicWallet, sinceVersion)
+}
+
+// WatchLineage subscribes to trust score and wallet changes across rootDID
+// and every identity it has spawned, directly or transitively. The lineage
+// is snapshotted at subscribe time; an identity spawned after the watch
+// begins is not picked up until the caller resubscribes. It returns a single
+// fanned-in channel of events from any descendant, an unsubscribe function
+// the caller must call exactly once when done, and the combined backlog of
+// buffered events newer than sinceVersion across all descendants.
+func (s *IdentityService) WatchLineage(ctx context.Context, rootDID string, sinceVersion uint64) (<-chan Event, func(), []Event, error) {
+	dids, err := s.repo.GetDescendants(ctx, rootDID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to resolve lineage for watch: %w", err)
+	}
+
+	out := make(chan Event, 32)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	var unsubscribes []func()
+	var backlog []Event
+
+	forward := func(ch <-chan Event) {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case e, ok := <-ch:
+				if !ok {
+					return
+				}
+				select {
+				case out <- e:
+				case <-stop:
+					return
+				}
+			}
+		}
+	}
+
+	for _, did := range dids {
+		for _, topic := range [...]EventTopic{TopicTrustScore, TopicWallet} {
+			ch, unsubscribe, bl := s.events.Subscribe(did, topic, sinceVersion)
+			backlog = append(backlog, bl...)
+			unsubscribes = append(unsubscribes, unsubscribe)
+			wg.Add(1)
+			go forward(ch)
+		}
+	}
+
+	unsubscribeAll := func() {
+		close(stop)
+		for _, unsubscribe := range unsubscribes {
+			unsubscribe()
+		}
+		wg.Wait()
+		close(out)
+	}
+
+	return out, unsubscribeAll, backlog, nil
+}
+
+// IssueCredential assembles a Verifiable Credential for subjectDID with the
+// given claims and expiration, and allocates it a status-list revocation
+// slot. Like VoteHalt, the issuer's signature is produced by the caller out
+// of band (IssueCredential does not sign with the issuer's derived key); IssueCredential
+// verifies signature against CanonicalPayload(credential) before returning
+// the assembled, ready-to-encode credential.
+func (s *IdentityService) IssueCredential(ctx context.Context, issuerDID, subjectDID string, claims map[string]any, expiration time.Time, signature []byte, format domain.CredentialFormat) (*domain.Credential, error) {
+	issuer, err := s.repo.GetIdentity(ctx, issuerDID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve issuer: %w", err)
+	}
+
+	index, err := s.repo.AllocateStatusListIndex(ctx, issuerDID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate status list index: %w", err)
+	}
+
+	cred := &domain.Credential{
+		ID:                   uuid.NewString(),
+		Issuer:               issuerDID,
+		Subject:              subjectDID,
+		Claims:               claims,
+		IssuanceDate:         time.Now().UTC(),
+		ExpirationDate:       expiration,
+		StatusListIndex:      index,
+		StatusListCredential: issuerDID,
+		Format:               format,
+	}
+
+	payload, err := vc.CanonicalPayload(cred)
+	if err != nil {
+		return nil, fmt.Errorf("failed to canonicalize credential: %w", err)
+	}
+
+	valid, err := verifySignatureForKeyType(issuer.KeyType, issuer.PublicKey, payload, signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify issuer signature: %w", err)
+	}
+	if !valid {
+		return nil, fmt.Errorf("invalid issuer signature for credential")
+	}
+
+	cred.Proof = signature
+	return cred, nil
+}
+
+// verifyCredentialProof resolves cred.Issuer's did:key and checks cred.Proof
+// against CanonicalPayload(cred).
+func (s *IdentityService) verifyCredentialProof(cred *domain.Credential) (bool, error) {
+	keyType, publicKey, err := s.ResolveDIDKey(cred.Issuer)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve issuer did: %w", err)
+	}
+	payload, err := vc.CanonicalPayload(cred)
 	if err != nil {
 		return false, err
 	}
+	return verifySignatureForKeyType(keyType, publicKey, payload, cred.Proof)
+}
+
+// VerifyCredential decodes a vc+jwt or JSON-LD credential, verifies its
+// issuer signature and expiration, and checks the issuer's status-list
+// registry for revocation without contacting the issuer. A successful,
+// unrevoked result is anchored to the subject's trust score via
+// RecordVerificationOutcome.
+func (s *IdentityService) VerifyCredential(ctx context.Context, raw string) (valid bool, issuerDID, subjectDID string, claims map[string]any, revoked bool, err error) {
+	cred, err := vc.Decode(raw)
+	if err != nil {
+		return false, "", "", nil, false, fmt.Errorf("failed to decode credential: %w", err)
+	}
+
+	proofValid, err := s.verifyCredentialProof(cred)
+	if err != nil {
+		return false, cred.Issuer, cred.Subject, cred.Claims, false, err
+	}
+
+	revoked, err = s.repo.IsCredentialRevoked(ctx, cred.StatusListCredential, cred.StatusListIndex)
+	if err != nil {
+		return false, cred.Issuer, cred.Subject, cred.Claims, false, fmt.Errorf("failed to check revocation status: %w", err)
+	}
+
+	valid = proofValid && !revoked && !cred.IsExpired(time.Now())
+
+	if valid {
+		if err := s.RecordVerificationOutcome(ctx, cred.Subject, true, 0); err != nil {
+			return valid, cred.Issuer, cred.Subject, cred.Claims, revoked, fmt.Errorf("credential verified but failed to record trust outcome: %w", err)
+		}
+	}
+
+	return valid, cred.Issuer, cred.Subject, cred.Claims, revoked, nil
+}
+
+// RevokeCredential marks a previously issued credential as revoked in its
+// issuer's status list. The caller supplies the full encoded credential
+// rather than a bare credential ID and status-list index: nothing is
+// persisted at issuance tying a credential ID to its (issuer, index), so a
+// signature over just the ID couldn't be checked against the index actually
+// being flipped. Requiring the credential lets its real StatusListIndex be
+// read back out of the signed document itself, and callerDID must match its
+// Issuer, proven by a signature over the credential's own canonical payload.
+func (s *IdentityService) RevokeCredential(ctx context.Context, callerDID, rawCredential string, signature []byte) error {
+	cred, err := vc.Decode(rawCredential)
+	if err != nil {
+		return fmt.Errorf("failed to decode credential: %w", err)
+	}
+	if cred.Issuer != callerDID {
+		return fmt.Errorf("callerDID %s is not the issuer of credential %s", callerDID, cred.ID)
+	}
+
+	payload, err := vc.CanonicalPayload(cred)
+	if err != nil {
+		return fmt.Errorf("failed to canonicalize credential: %w", err)
+	}
+	valid, err := s.VerifyDIDSignature(ctx, callerDID, payload, signature)
+	if err != nil {
+		return fmt.Errorf("failed to verify issuer signature: %w", err)
+	}
+	if !valid {
+		return fmt.Errorf("invalid issuer signature for credential %s", cred.ID)
+	}
+	return s.repo.RevokeCredential(ctx, callerDID, cred.StatusListIndex)
+}
+
+// VerifyPresentation verifies a holder's Verifiable Presentation: the
+// holder's proof over {challenge, domain, credential IDs} (binding the
+// presentation to this verifier and preventing replay elsewhere), plus each
+// embedded credential's own issuer proof, expiration, and revocation status.
+// Embedded credentials are not individually anchored to trust scores here —
+// only VerifyCredential does that — since a presentation may show the same
+// credential to many verifiers.
+func (s *IdentityService) VerifyPresentation(ctx context.Context, raw, challenge, verifierDomain string) (valid bool, holderDID string, credentials []domain.Credential, err error) {
+	pres, encodedCreds, err := vc.DecodePresentation([]byte(raw))
+	if err != nil {
+		return false, "", nil, fmt.Errorf("failed to decode presentation: %w", err)
+	}
+	pres.Challenge = challenge
+	pres.Domain = verifierDomain
+
+	for _, encoded := range encodedCreds {
+		cred, err := vc.Decode(encoded)
+		if err != nil {
+			return false, pres.Holder, nil, fmt.Errorf("failed to decode embedded credential: %w", err)
+		}
+		pres.Credentials = append(pres.Credentials, *cred)
+	}
+
+	holderKeyType, holderKey, err := s.ResolveDIDKey(pres.Holder)
+	if err != nil {
+		return false, pres.Holder, pres.Credentials, fmt.Errorf("failed to resolve holder did: %w", err)
+	}
+	payload, err := vc.PresentationCanonicalPayload(pres)
+	if err != nil {
+		return false, pres.Holder, pres.Credentials, err
+	}
+	holderValid, err := verifySignatureForKeyType(holderKeyType, holderKey, payload, pres.Proof)
+	if err != nil {
+		return false, pres.Holder, pres.Credentials, fmt.Errorf("failed to verify holder proof: %w", err)
+	}
+	if !holderValid {
+		return false, pres.Holder, pres.Credentials, nil
+	}
+
+	now := time.Now()
+	for i := range pres.Credentials {
+		cred := &pres.Credentials[i]
+
+		proofValid, err := s.verifyCredentialProof(cred)
+		if err != nil {
+			return false, pres.Holder, pres.Credentials, err
+		}
+		if !proofValid || cred.IsExpired(now) {
+			return false, pres.Holder, pres.Credentials, nil
+		}
+
+		revoked, err := s.repo.IsCredentialRevoked(ctx, cred.StatusListCredential, cred.StatusListIndex)
+		if err != nil {
+			return false, pres.Holder, pres.Credentials, fmt.Errorf("failed to check revocation status: %w", err)
+		}
+		if revoked {
+			return false, pres.Holder, pres.Credentials, nil
+		}
+	}
+
+	return true, pres.Holder, pres.Credentials, nil
+}
+
+// RegisterWebhook persists a new webhook subscription. eventTypes filters
+// which WebhookEventType values it receives (empty means every type);
+// didFilter, if non-nil, further restricts it to events about one DID. url
+// must be a public https address: rejecting loopback, link-local, and
+// private-range targets here keeps the dispatcher's retried POSTs from
+// being usable to reach internal services or cloud metadata endpoints.
+func (s *IdentityService) RegisterWebhook(ctx context.Context, url, secret string, eventTypes []domain.WebhookEventType, didFilter *string) (*domain.WebhookSubscription, error) {
+	if err := httpguard.ValidateURL(url); err != nil {
+		return nil, fmt.Errorf("invalid webhook url: %w", err)
+	}
 
-	if len(identity.PublicKey) != ed25519.PublicKeySize {
-		return false, fmt.Errorf("invalid public key length")
+	sub := &domain.WebhookSubscription{
+		ID:         uuid.New(),
+		URL:        url,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		DIDFilter:  didFilter,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := s.repo.CreateWebhookSubscription(ctx, sub); err != nil {
+		return nil, fmt.Errorf("failed to register webhook: %w", err)
 	}
+	return sub, nil
+}
+
+// DeregisterWebhook removes a previously registered subscription.
+func (s *IdentityService) DeregisterWebhook(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteWebhookSubscription(ctx, id)
+}
 
-	return ed25519.Verify(identity.PublicKey, message, signature), nil
+// ListDeadLetterWebhooks returns every webhook delivery that exhausted its
+// retries, for the admin dead-letter listing RPC. It returns nil if no
+// dispatcher is configured.
+func (s *IdentityService) ListDeadLetterWebhooks() []webhooks.DeadLetter {
+	if s.webhooks == nil {
+		return nil
+	}
+	return s.webhooks.DeadLetters()
 }
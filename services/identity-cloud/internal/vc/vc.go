@@ -0,0 +1,336 @@
+// Package vc encodes and decodes W3C Verifiable Credentials and
+// Presentations in both the compact vc+jwt and embedded JSON-LD proof
+// formats. It only handles serialization and canonicalization; signing and
+// signature verification are the caller's responsibility (see
+// service.IdentityService.IssueCredential/VerifyCredential), matching how
+// IssueCredential takes the issuer's signature from the caller.
+//
+// Simplification: unlike a spec-compliant JOSE JWT, the proof in both
+// formats always signs CanonicalPayload(credential) rather than a
+// format-specific signing input (e.g. base64url(header).base64url(payload)
+// for JWT). This keeps issuance and verification symmetric across formats
+// without requiring a full JOSE/JSON-LD suite implementation.
+package vc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/actoris/actoris/services/identity-cloud/internal/domain"
+)
+
+// claimSet is the canonical, deterministically-ordered payload an issuer
+// signs over. Go's encoding/json marshals struct fields in declaration
+// order, which is what makes this deterministic across calls.
+type claimSet struct {
+	ID                   string         `json:"id"`
+	Issuer               string         `json:"issuer"`
+	Subject              string         `json:"subject"`
+	Claims               map[string]any `json:"claims"`
+	IssuanceDate         int64          `json:"issuance_date"`
+	ExpirationDate       int64          `json:"expiration_date,omitempty"`
+	StatusListIndex      uint32         `json:"status_list_index"`
+	StatusListCredential string         `json:"status_list_credential"`
+}
+
+func toClaimSet(c *domain.Credential) claimSet {
+	cs := claimSet{
+		ID:                   c.ID,
+		Issuer:               c.Issuer,
+		Subject:              c.Subject,
+		Claims:               c.Claims,
+		IssuanceDate:         c.IssuanceDate.UnixMilli(),
+		StatusListIndex:      c.StatusListIndex,
+		StatusListCredential: c.StatusListCredential,
+	}
+	if !c.ExpirationDate.IsZero() {
+		cs.ExpirationDate = c.ExpirationDate.UnixMilli()
+	}
+	return cs
+}
+
+func fromClaimSet(cs claimSet) *domain.Credential {
+	cred := &domain.Credential{
+		ID:                   cs.ID,
+		Issuer:               cs.Issuer,
+		Subject:              cs.Subject,
+		Claims:               cs.Claims,
+		IssuanceDate:         time.UnixMilli(cs.IssuanceDate).UTC(),
+		StatusListIndex:      cs.StatusListIndex,
+		StatusListCredential: cs.StatusListCredential,
+	}
+	if cs.ExpirationDate != 0 {
+		cred.ExpirationDate = time.UnixMilli(cs.ExpirationDate).UTC()
+	}
+	return cred
+}
+
+// CanonicalPayload returns the deterministic byte sequence an issuer signs
+// and a verifier re-derives to check that signature.
+func CanonicalPayload(c *domain.Credential) ([]byte, error) {
+	return json.Marshal(toClaimSet(c))
+}
+
+func algForKeyType(kt domain.KeyType) string {
+	switch kt {
+	case domain.KeyTypeEd25519:
+		return "EdDSA"
+	case domain.KeyTypeP256:
+		return "ES256"
+	case domain.KeyTypeSecp256k1:
+		return "ES256K"
+	default:
+		return ""
+	}
+}
+
+func ldProofType(kt domain.KeyType) string {
+	switch kt {
+	case domain.KeyTypeEd25519:
+		return "Ed25519Signature2020"
+	case domain.KeyTypeP256:
+		return "EcdsaSecp256r1Signature2019"
+	case domain.KeyTypeSecp256k1:
+		return "EcdsaSecp256k1Signature2019"
+	default:
+		return ""
+	}
+}
+
+// EncodeJWT serializes c as a compact vc+jwt:
+// base64url(header).base64url(payload).base64url(c.Proof).
+func EncodeJWT(c *domain.Credential, issuerKeyType domain.KeyType) (string, error) {
+	header := map[string]string{"typ": "vc+jwt", "alg": algForKeyType(issuerKeyType)}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	payloadJSON, err := CanonicalPayload(c)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		base64.RawURLEncoding.EncodeToString(headerJSON),
+		base64.RawURLEncoding.EncodeToString(payloadJSON),
+		base64.RawURLEncoding.EncodeToString(c.Proof),
+	}, "."), nil
+}
+
+// DecodeJWT parses a compact vc+jwt back into a Credential, including its
+// signature in Proof.
+func DecodeJWT(token string) (*domain.Credential, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed vc+jwt: expected 3 segments, got %d", len(parts))
+	}
+	payloadJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid vc+jwt payload encoding: %w", err)
+	}
+	var cs claimSet
+	if err := json.Unmarshal(payloadJSON, &cs); err != nil {
+		return nil, fmt.Errorf("invalid vc+jwt payload: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid vc+jwt signature encoding: %w", err)
+	}
+
+	cred := fromClaimSet(cs)
+	cred.Format = domain.CredentialFormatJWT
+	cred.Proof = signature
+	return cred, nil
+}
+
+type ldProof struct {
+	Type               string `json:"type"`
+	Created            string `json:"created"`
+	VerificationMethod string `json:"verificationMethod"`
+	ProofValue         string `json:"proofValue"`
+}
+
+type credentialStatus struct {
+	Type                 string `json:"type"`
+	StatusListIndex      uint32 `json:"statusListIndex"`
+	StatusListCredential string `json:"statusListCredential"`
+}
+
+type jsonLDCredential struct {
+	Context           []string           `json:"@context"`
+	ID                string             `json:"id"`
+	Type              []string           `json:"type"`
+	Issuer            string             `json:"issuer"`
+	IssuanceDate      string             `json:"issuanceDate"`
+	ExpirationDate    string             `json:"expirationDate,omitempty"`
+	CredentialSubject map[string]any     `json:"credentialSubject"`
+	CredentialStatus  *credentialStatus  `json:"credentialStatus,omitempty"`
+	Proof             *ldProof           `json:"proof"`
+}
+
+// EncodeJSONLD serializes c as a JSON-LD document with an embedded proof.
+func EncodeJSONLD(c *domain.Credential, issuerKeyType domain.KeyType) ([]byte, error) {
+	subject := make(map[string]any, len(c.Claims)+1)
+	subject["id"] = c.Subject
+	for k, v := range c.Claims {
+		subject[k] = v
+	}
+
+	doc := jsonLDCredential{
+		Context:           []string{"https://www.w3.org/2018/credentials/v1"},
+		ID:                c.ID,
+		Type:              []string{"VerifiableCredential"},
+		Issuer:            c.Issuer,
+		IssuanceDate:      c.IssuanceDate.UTC().Format(time.RFC3339),
+		CredentialSubject: subject,
+		CredentialStatus: &credentialStatus{
+			Type:                 "StatusList2021Entry",
+			StatusListIndex:      c.StatusListIndex,
+			StatusListCredential: c.StatusListCredential,
+		},
+		Proof: &ldProof{
+			Type:               ldProofType(issuerKeyType),
+			Created:            c.IssuanceDate.UTC().Format(time.RFC3339),
+			VerificationMethod: c.Issuer + "#key-1",
+			ProofValue:         base64.RawURLEncoding.EncodeToString(c.Proof),
+		},
+	}
+	if !c.ExpirationDate.IsZero() {
+		doc.ExpirationDate = c.ExpirationDate.UTC().Format(time.RFC3339)
+	}
+	return json.Marshal(doc)
+}
+
+// DecodeJSONLD parses a JSON-LD verifiable credential document back into a
+// Credential, including its signature in Proof.
+func DecodeJSONLD(raw []byte) (*domain.Credential, error) {
+	var doc jsonLDCredential
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON-LD credential: %w", err)
+	}
+	if doc.Proof == nil {
+		return nil, fmt.Errorf("JSON-LD credential is missing a proof")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(doc.Proof.ProofValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proofValue encoding: %w", err)
+	}
+
+	subject, _ := doc.CredentialSubject["id"].(string)
+	claims := make(map[string]any, len(doc.CredentialSubject))
+	for k, v := range doc.CredentialSubject {
+		if k != "id" {
+			claims[k] = v
+		}
+	}
+
+	issuanceDate, _ := time.Parse(time.RFC3339, doc.IssuanceDate)
+	var expirationDate time.Time
+	if doc.ExpirationDate != "" {
+		expirationDate, _ = time.Parse(time.RFC3339, doc.ExpirationDate)
+	}
+
+	cred := &domain.Credential{
+		ID:             doc.ID,
+		Issuer:         doc.Issuer,
+		Subject:        subject,
+		Claims:         claims,
+		IssuanceDate:   issuanceDate,
+		ExpirationDate: expirationDate,
+		Format:         domain.CredentialFormatJSONLD,
+		Proof:          signature,
+	}
+	if doc.CredentialStatus != nil {
+		cred.StatusListIndex = doc.CredentialStatus.StatusListIndex
+		cred.StatusListCredential = doc.CredentialStatus.StatusListCredential
+	}
+	return cred, nil
+}
+
+// Encode serializes c in its own Format.
+func Encode(c *domain.Credential, issuerKeyType domain.KeyType) (string, error) {
+	switch c.Format {
+	case domain.CredentialFormatJWT:
+		return EncodeJWT(c, issuerKeyType)
+	case domain.CredentialFormatJSONLD:
+		raw, err := EncodeJSONLD(c, issuerKeyType)
+		if err != nil {
+			return "", err
+		}
+		return string(raw), nil
+	default:
+		return "", fmt.Errorf("unsupported credential format: %s", c.Format)
+	}
+}
+
+// Decode parses raw as either a compact vc+jwt or a JSON-LD credential
+// document, sniffing the format from its shape.
+func Decode(raw string) (*domain.Credential, error) {
+	trimmed := strings.TrimSpace(raw)
+	if strings.HasPrefix(trimmed, "{") {
+		return DecodeJSONLD([]byte(trimmed))
+	}
+	return DecodeJWT(trimmed)
+}
+
+// presentationClaimSet is the canonical payload a holder signs over to bind
+// a presentation to a specific verifier's challenge and domain.
+type presentationClaimSet struct {
+	Holder        string   `json:"holder"`
+	Challenge     string   `json:"challenge"`
+	Domain        string   `json:"domain"`
+	CredentialIDs []string `json:"credential_ids"`
+}
+
+// PresentationCanonicalPayload returns the deterministic byte sequence a
+// holder signs and a verifier re-derives to check the presentation proof.
+func PresentationCanonicalPayload(p *domain.Presentation) ([]byte, error) {
+	ids := make([]string, len(p.Credentials))
+	for i, c := range p.Credentials {
+		ids[i] = c.ID
+	}
+	return json.Marshal(presentationClaimSet{
+		Holder:        p.Holder,
+		Challenge:     p.Challenge,
+		Domain:        p.Domain,
+		CredentialIDs: ids,
+	})
+}
+
+// jsonLDPresentation is the wire shape of a Verifiable Presentation: the
+// holder's proof plus each embedded credential, still in its own encoded
+// form (vc+jwt or JSON-LD) so it can be independently verified.
+type jsonLDPresentation struct {
+	Context              []string `json:"@context"`
+	Type                 []string `json:"type"`
+	Holder               string   `json:"holder"`
+	VerifiableCredential []string `json:"verifiableCredential"`
+	Proof                *ldProof `json:"proof"`
+}
+
+// DecodePresentation parses a Verifiable Presentation document, returning
+// the presentation shell (holder, challenge/domain are filled in by the
+// caller from the verifier's own request, not trusted from the document)
+// plus the still-encoded credential strings for the caller to Decode and
+// verify individually.
+func DecodePresentation(raw []byte) (*domain.Presentation, []string, error) {
+	var doc jsonLDPresentation
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, nil, fmt.Errorf("invalid verifiable presentation: %w", err)
+	}
+	if doc.Proof == nil {
+		return nil, nil, fmt.Errorf("verifiable presentation is missing a proof")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(doc.Proof.ProofValue)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid proofValue encoding: %w", err)
+	}
+
+	pres := &domain.Presentation{
+		Holder: doc.Holder,
+		Proof:  signature,
+	}
+	return pres, doc.VerifiableCredential, nil
+}
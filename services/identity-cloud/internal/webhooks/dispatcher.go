@@ -0,0 +1,208 @@
+// Package webhooks delivers domain events to externally registered HTTP
+// endpoints, giving downstream services (pricing, fraud, billing) a
+// push-based integration point instead of polling GetTrustScore/GetHCWallet.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/actoris/actoris/services/identity-cloud/internal/domain"
+	"github.com/actoris/actoris/services/identity-cloud/internal/httpguard"
+	"github.com/actoris/actoris/services/identity-cloud/internal/repository"
+)
+
+// deliveryQueueCapacity bounds how many deliveries can be buffered waiting
+// for a worker; Emit drops a delivery rather than blocking its caller once
+// the queue is full, since webhook delivery is at-least-once best-effort,
+// not something identity mutations can depend on for correctness.
+const deliveryQueueCapacity = 256
+
+// deliveryWorkers is the number of goroutines pulling deliveries off the
+// queue concurrently.
+const deliveryWorkers = 4
+
+// delivery is one attempt, or retry, to deliver event to subscription.
+type delivery struct {
+	subscription domain.WebhookSubscription
+	event        domain.WebhookEvent
+	attempt      int
+}
+
+// DeadLetter is a delivery that exhausted every retry attempt, retained in
+// memory for the admin dead-letter listing RPC.
+type DeadLetter struct {
+	Subscription domain.WebhookSubscription
+	Event        domain.WebhookEvent
+	Attempts     int
+	LastError    string
+	FailedAt     time.Time
+}
+
+// Dispatcher fans webhook events out to every matching subscription,
+// delivering each over HTTP with an HMAC-signed body and retrying failures
+// with exponential backoff before giving up and parking the delivery in its
+// in-memory dead-letter store. A Dispatcher is only suitable for a single
+// replica; a horizontally scaled deployment needs a shared retry queue,
+// mirroring the idempotency package's MemoryStore/PostgresStore split.
+type Dispatcher struct {
+	repo        *repository.Neo4jRepository
+	client      *http.Client
+	maxAttempts int
+	baseBackoff time.Duration
+
+	queue chan delivery
+
+	mu          sync.Mutex
+	deadLetters []DeadLetter
+}
+
+// NewDispatcher creates a Dispatcher and starts its delivery workers. ctx
+// governs the workers' lifetime; they exit once ctx is canceled. A nil
+// client defaults to http.DefaultClient. The client is wrapped with
+// httpguard.Guard, which pins every dial to the address it validates rather
+// than letting the request's real connection re-resolve DNS on its own, so
+// a subscription URL that validated at registration time (see
+// service.RegisterWebhook) can't redirect deliveries to a disallowed
+// address afterward, or be rebound to one between validation and delivery.
+func NewDispatcher(ctx context.Context, repo *repository.Neo4jRepository, client *http.Client, maxAttempts int, baseBackoff time.Duration) *Dispatcher {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	d := &Dispatcher{
+		repo:        repo,
+		client:      httpguard.Guard(client),
+		maxAttempts: maxAttempts,
+		baseBackoff: baseBackoff,
+		queue:       make(chan delivery, deliveryQueueCapacity),
+	}
+	for i := 0; i < deliveryWorkers; i++ {
+		go d.worker(ctx)
+	}
+	return d
+}
+
+// Emit looks up every subscription matching event and enqueues a delivery
+// for each. It never blocks or returns an error: a lookup failure or a full
+// queue simply means the event isn't delivered this time, consistent with
+// the service package's publishTrustScoreEvent/publishWalletEvent choosing
+// to swallow errors rather than unwind an already-committed mutation.
+func (d *Dispatcher) Emit(ctx context.Context, event domain.WebhookEvent) {
+	subs, err := d.repo.ListWebhookSubscriptions(ctx)
+	if err != nil {
+		return
+	}
+	for _, sub := range subs {
+		if !sub.Matches(event) {
+			continue
+		}
+		select {
+		case d.queue <- delivery{subscription: sub, event: event, attempt: 1}:
+		default:
+		}
+	}
+}
+
+func (d *Dispatcher) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-d.queue:
+			d.attempt(ctx, job)
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, job delivery) {
+	err := d.deliver(ctx, job.subscription, job.event)
+	if err == nil {
+		return
+	}
+	if job.attempt >= d.maxAttempts {
+		d.deadLetter(job, err)
+		return
+	}
+
+	backoff := d.baseBackoff * time.Duration(1<<uint(job.attempt-1))
+	time.AfterFunc(backoff, func() {
+		select {
+		case d.queue <- delivery{subscription: job.subscription, event: job.event, attempt: job.attempt + 1}:
+		default:
+		}
+	})
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, sub domain.WebhookSubscription, event domain.WebhookEvent) error {
+	// Re-validated on every delivery, not just at registration: DNS for
+	// sub.URL's host can change between when it was registered and now, and
+	// a delivery can be retried hours later. This is a fast, early rejection
+	// for an obviously disallowed URL (wrong scheme, already-private host) --
+	// the actual connection is additionally protected by d.client's pinned
+	// dial (see httpguard.Guard), since a host that resolves safely right
+	// here could still be rebound by the time the request actually connects.
+	if err := httpguard.ValidateURL(sub.URL); err != nil {
+		return fmt.Errorf("webhook delivery blocked: %w", err)
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Actoris-Signature", "sha256="+sign(sub.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook delivery failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sign returns the lowercase-hex HMAC-SHA256 of body keyed by secret, sent
+// as the value following "sha256=" in the X-Actoris-Signature header.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) deadLetter(job delivery, lastErr error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deadLetters = append(d.deadLetters, DeadLetter{
+		Subscription: job.subscription,
+		Event:        job.event,
+		Attempts:     job.attempt,
+		LastError:    lastErr.Error(),
+		FailedAt:     time.Now().UTC(),
+	})
+}
+
+// DeadLetters returns every delivery that exhausted its retries, oldest
+// first, for the admin dead-letter listing RPC.
+func (d *Dispatcher) DeadLetters() []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]DeadLetter, len(d.deadLetters))
+	copy(out, d.deadLetters)
+	return out
+}
@@ -2,10 +2,14 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/actoris/actoris/services/identity-cloud/internal/keys"
 )
 
 // Config holds all configuration for IdentityCloud
@@ -18,6 +22,34 @@ type Config struct {
 
 	// Service settings
 	Service ServiceConfig
+
+	// Metrics settings
+	Metrics MetricsConfig
+
+	// Tracing settings
+	Tracing TracingConfig
+}
+
+// MetricsConfig holds the Prometheus /metrics HTTP listener configuration.
+// It's deliberately a separate listener from the gRPC server so scraping
+// metrics never competes with the gRPC request path.
+type MetricsConfig struct {
+	Host string
+	Port int
+}
+
+// Address returns the metrics HTTP listener address
+func (c *MetricsConfig) Address() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// TracingConfig holds OpenTelemetry tracing configuration
+type TracingConfig struct {
+	// ServiceName identifies this service in exported spans
+	ServiceName string
+	// OTLPEndpoint is the collector address spans are exported to, e.g.
+	// "localhost:4317"
+	OTLPEndpoint string
 }
 
 // ServerConfig holds gRPC server configuration
@@ -31,7 +63,9 @@ type ServerConfig struct {
 
 // Neo4jConfig holds Neo4j connection configuration
 type Neo4jConfig struct {
-	URI      string
+	// URIs is the list of Neo4j endpoints to fail over between, e.g.
+	// ["bolt://a:7687", "bolt://b:7687", "neo4j+s://c:7687"]
+	URIs     []string
 	Username string
 	Password string
 	Database string
@@ -47,6 +81,36 @@ type ServiceConfig struct {
 	EnableTrustInheritance bool
 	// Default trust score for new identities
 	DefaultTrustScore uint32
+	// DIDs of the governance signers authorized to vote on wallet halts
+	GovernanceDIDs []string
+	// How long an idempotency key stays valid for replay/conflict detection
+	IdempotencyTTL time.Duration
+	// Minimum sibling cohort size (inclusive of the ancestor) required before
+	// lineage trust inheritance trims outliers and averages; smaller cohorts
+	// fall back to the ancestor's own tau for that generation
+	MinCohortSize int
+	// MasterSeed is the 32-byte root secret every identity's Ed25519 keypair
+	// is deterministically derived from; see internal/keys. Required.
+	MasterSeed []byte
+	// TrustScoreThresholds are the score values that trigger a
+	// trust_score.threshold_crossed webhook when a ClaimTrustDelta call moves
+	// a DID's score from one side to the other; the claim a trust-score read
+	// folds in does not trigger it.
+	TrustScoreThresholds []uint32
+	// WebhookMaxAttempts is how many delivery attempts, the first one
+	// included, the webhook dispatcher makes before parking a failed
+	// delivery in the dead-letter store.
+	WebhookMaxAttempts int
+	// WebhookBaseBackoff is the delay before the first webhook delivery
+	// retry; each subsequent retry doubles it.
+	WebhookBaseBackoff time.Duration
+	// IdempotencyPurgeInterval is how often the server sweeps HCOperation
+	// idempotency records older than IdempotencyPurgeMaxAge.
+	IdempotencyPurgeInterval time.Duration
+	// IdempotencyPurgeMaxAge is how old an HCOperation record must be before
+	// the periodic sweep deletes it; should comfortably exceed the longest
+	// client retry window.
+	IdempotencyPurgeMaxAge time.Duration
 }
 
 // Load loads configuration from environment variables
@@ -60,24 +124,60 @@ func Load() (*Config, error) {
 			ShutdownTimeout: time.Duration(getEnvInt("IDENTITY_SHUTDOWN_TIMEOUT_SECS", 30)) * time.Second,
 		},
 		Neo4j: Neo4jConfig{
-			URI:      getEnv("NEO4J_URI", "bolt://localhost:7687"),
+			// NEO4J_URIS is a comma-separated endpoint list; NEO4J_URI is
+			// kept as a single-endpoint fallback for existing deployments.
+			URIs:     getEnvList("NEO4J_URIS", []string{getEnv("NEO4J_URI", "bolt://localhost:7687")}),
 			Username: getEnv("NEO4J_USERNAME", "neo4j"),
 			Password: getEnv("NEO4J_PASSWORD", ""),
 			Database: getEnv("NEO4J_DATABASE", "neo4j"),
 		},
 		Service: ServiceConfig{
-			InitialHCBalance:       getEnv("IDENTITY_INITIAL_HC_BALANCE", "0"),
-			WalletExpiryDays:       getEnvInt("IDENTITY_WALLET_EXPIRY_DAYS", 30),
-			EnableTrustInheritance: getEnvBool("IDENTITY_ENABLE_TRUST_INHERITANCE", true),
-			DefaultTrustScore:      uint32(getEnvInt("IDENTITY_DEFAULT_TRUST_SCORE", 500)),
+			InitialHCBalance:         getEnv("IDENTITY_INITIAL_HC_BALANCE", "0"),
+			WalletExpiryDays:         getEnvInt("IDENTITY_WALLET_EXPIRY_DAYS", 30),
+			EnableTrustInheritance:   getEnvBool("IDENTITY_ENABLE_TRUST_INHERITANCE", true),
+			DefaultTrustScore:        uint32(getEnvInt("IDENTITY_DEFAULT_TRUST_SCORE", 500)),
+			GovernanceDIDs:           getEnvList("IDENTITY_GOVERNANCE_DIDS", nil),
+			IdempotencyTTL:           time.Duration(getEnvInt("IDENTITY_IDEMPOTENCY_TTL_SECS", 86400)) * time.Second,
+			MinCohortSize:            getEnvInt("IDENTITY_MIN_COHORT_SIZE", 4),
+			WebhookMaxAttempts:       getEnvInt("IDENTITY_WEBHOOK_MAX_ATTEMPTS", 5),
+			WebhookBaseBackoff:       time.Duration(getEnvInt("IDENTITY_WEBHOOK_BASE_BACKOFF_SECS", 2)) * time.Second,
+			IdempotencyPurgeInterval: time.Duration(getEnvInt("IDENTITY_IDEMPOTENCY_PURGE_INTERVAL_SECS", 86400)) * time.Second,
+			IdempotencyPurgeMaxAge:   time.Duration(getEnvInt("IDENTITY_IDEMPOTENCY_PURGE_MAX_AGE_SECS", 7*86400)) * time.Second,
+		},
+		Metrics: MetricsConfig{
+			Host: getEnv("IDENTITY_METRICS_HOST", "0.0.0.0"),
+			Port: getEnvInt("IDENTITY_METRICS_PORT", 9090),
+		},
+		Tracing: TracingConfig{
+			ServiceName:  getEnv("IDENTITY_TRACING_SERVICE_NAME", "identity-cloud"),
+			OTLPEndpoint: getEnv("IDENTITY_TRACING_OTLP_ENDPOINT", "localhost:4317"),
 		},
 	}
 
+	thresholds, err := getEnvUint32List("IDENTITY_TRUST_SCORE_THRESHOLDS", nil)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Service.TrustScoreThresholds = thresholds
+
 	// Validate required config
 	if cfg.Neo4j.Password == "" {
 		return nil, fmt.Errorf("NEO4J_PASSWORD environment variable is required")
 	}
 
+	masterSeedB64 := os.Getenv("IDENTITY_MASTER_SEED")
+	if masterSeedB64 == "" {
+		return nil, fmt.Errorf("IDENTITY_MASTER_SEED environment variable is required")
+	}
+	masterSeed, err := base64.StdEncoding.DecodeString(masterSeedB64)
+	if err != nil {
+		return nil, fmt.Errorf("IDENTITY_MASTER_SEED must be base64-encoded: %w", err)
+	}
+	if len(masterSeed) != keys.SeedSize {
+		return nil, fmt.Errorf("IDENTITY_MASTER_SEED must decode to %d bytes, got %d", keys.SeedSize, len(masterSeed))
+	}
+	cfg.Service.MasterSeed = masterSeed
+
 	return cfg, nil
 }
 
@@ -113,3 +213,45 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+// getEnvUint32List gets a comma-separated environment variable as a slice of
+// uint32, with a default value. An empty entry is skipped; a malformed one
+// is reported as an error rather than silently ignored, since a typo'd
+// threshold would otherwise just never fire.
+func getEnvUint32List(key string, defaultValue []uint32) ([]uint32, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parts := strings.Split(value, ",")
+	list := make([]uint32, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid threshold %q: %w", key, p, err)
+		}
+		list = append(list, uint32(n))
+	}
+	return list, nil
+}
+
+// getEnvList gets a comma-separated environment variable as a string slice,
+// trimming whitespace around each entry, with a default value
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parts := strings.Split(value, ",")
+	list := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			list = append(list, p)
+		}
+	}
+	return list
+}